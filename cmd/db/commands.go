@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/JubaerHossain/rootx"
+	"github.com/JubaerHossain/rootx/pkg/core/database/migration"
+	"github.com/JubaerHossain/rootx/pkg/core/database/seed"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/viper"
+)
+
+// migrateCommand runs "migrate up|down|status|redo"
+func migrateCommand(opts *options, args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("migrate requires one of: up, down, status, redo")
+	}
+
+	action, args := args[0], args[1:]
+	switch action {
+	case "up":
+		return migrateUp(opts, args)
+	case "down":
+		return migrateDown(opts, args)
+	case "status":
+		return migrateStatus(opts, args)
+	case "redo":
+		return migrateRedo(opts, args)
+	default:
+		return usageErrorf("unknown migrate action %q", action)
+	}
+}
+
+func migrateUp(opts *options, args []string) error {
+	flags := newFlagSet("migrate up", opts)
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	return withPool(opts, func(pool *pgxpool.Pool) error {
+		return applyMigrations(opts, pool)
+	})
+}
+
+func migrateDown(opts *options, args []string) error {
+	flags := newFlagSet("migrate down", opts)
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	to := flags.String("to", "", "roll back every migration applied after this version (0 for all)")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	stepsSet := false
+	flags.Visit(func(f *flag.Flag) { stepsSet = stepsSet || f.Name == "steps" })
+	if stepsSet && *to != "" {
+		return usageErrorf("--steps and --to cannot be combined")
+	}
+
+	if err := confirm(opts, "roll back migrations"); err != nil {
+		return err
+	}
+
+	return withPool(opts, func(pool *pgxpool.Pool) error {
+		return rollbackMigrations(opts, pool, migration.RollbackOptions{Steps: *steps, To: *to})
+	})
+}
+
+func migrateRedo(opts *options, args []string) error {
+	flags := newFlagSet("migrate redo", opts)
+	steps := flags.Int("steps", 1, "number of migrations to roll back and re-apply")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := confirm(opts, "roll back and re-apply migrations"); err != nil {
+		return err
+	}
+
+	return withPool(opts, func(pool *pgxpool.Pool) error {
+		if err := rollbackMigrations(opts, pool, migration.RollbackOptions{Steps: *steps}); err != nil {
+			return err
+		}
+		return applyMigrations(opts, pool)
+	})
+}
+
+func migrateStatus(opts *options, args []string) error {
+	flags := newFlagSet("migrate status", opts)
+	drift := flags.Bool("drift", false, "compare the live schema against the committed snapshot")
+	snapshot := flags.String("snapshot", migration.SnapshotFile, "schema snapshot to compare against")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	return withPool(opts, func(pool *pgxpool.Pool) error {
+		ctx := context.Background()
+		migrator, err := newMigrator(opts, pool)
+		if err != nil {
+			return err
+		}
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		colors := map[string]string{"applied": "32", "pending": "33", "modified": "31", "missing": "31"}
+		problems := 0
+		for _, status := range statuses {
+			appliedAt := "-"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			state := status.State()
+			if state == "modified" || state == "missing" {
+				problems++
+			}
+			fmt.Printf("\x1b[%sm%-9s\x1b[0m %-45s %-20s %s\n", colors[state], state, status.Name, appliedAt, status.Checksum[:12])
+		}
+
+		if *drift {
+			expected, err := migration.LoadSnapshot(*snapshot)
+			if err != nil {
+				return err
+			}
+			actual, err := migration.InspectSchema(ctx, pool)
+			if err != nil {
+				return err
+			}
+
+			diff := migration.Diff(expected, actual)
+			if len(diff) == 0 {
+				fmt.Println("\nNo schema drift detected")
+			} else {
+				fmt.Printf("\nSchema drift against %s:\n", *snapshot)
+				for _, line := range diff {
+					fmt.Println("  " + line)
+				}
+				problems++
+			}
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("migrations are out of sync with the database")
+		}
+		return nil
+	})
+}
+
+func schemaDumpCommand(opts *options, args []string) error {
+	flags := newFlagSet("schema:dump", opts)
+	snapshot := flags.String("snapshot", migration.SnapshotFile, "file to write the schema snapshot to")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	return withPool(opts, func(pool *pgxpool.Pool) error {
+		schema, err := migration.InspectSchema(context.Background(), pool)
+		if err != nil {
+			return err
+		}
+		if err := migration.WriteSnapshot(*snapshot, schema); err != nil {
+			return err
+		}
+		fmt.Printf("Schema snapshot written: %s\n", *snapshot)
+		return nil
+	})
+}
+
+func makeMigrationCommand(opts *options, args []string) error {
+	flags := newFlagSet("make:migration", opts)
+	withSeeder := flags.Bool("seeder", false, "also create a seeder for the table")
+	fromEntity := flags.String("from-entity", "", "generate the table of an entity struct, e.g. domain/entity.User")
+	table := flags.String("table", "", "table of the entity (default the plural snake case of the type)")
+	snapshot := flags.String("snapshot", migration.SnapshotFile, "schema snapshot the entity is compared with")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if *fromEntity != "" {
+		if len(positional) > 1 || *withSeeder {
+			return usageErrorf("make:migration --from-entity takes at most a migration name and no --seeder")
+		}
+		name := ""
+		if len(positional) == 1 {
+			name = positional[0]
+		}
+		return createMigrationFromEntity(*fromEntity, name, *table, *snapshot)
+	}
+
+	if len(positional) != 1 {
+		return usageErrorf("make:migration requires exactly one migration name")
+	}
+
+	if *withSeeder {
+		return createMigrationFileWithSeeder(positional[0])
+	}
+	return createMigrationFile(positional[0])
+}
+
+func seedCommand(opts *options, args []string) error {
+	flags := newFlagSet("seed", opts)
+	name := flags.String("name", "", "run a single seeder and its dependencies, e.g. users or dev/seed_users.sql")
+	flags.StringVar(name, "only", "", "alias of --name")
+	count := flags.Int("count", 0, "number of rows the Go seeders create (default per seeder)")
+	force := flags.Bool("force", false, "run seeders again even when they already ran")
+	env := flags.String("env", "", "environment whose scoped seeds run, dev|test|prod (default from APP_ENV)")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *count < 0 {
+		return usageErrorf("--count must not be negative")
+	}
+
+	return withPool(opts, func(pool *pgxpool.Pool) error {
+		return runSeeders(opts, pool, *env, seed.RunOptions{Only: *name, Force: *force, Params: seed.Params{Count: *count}})
+	})
+}
+
+func resetCommand(opts *options, args []string) error {
+	flags := newFlagSet("reset", opts)
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := confirm(opts, "roll back every migration and apply them again"); err != nil {
+		return err
+	}
+
+	return withPool(opts, func(pool *pgxpool.Pool) error {
+		if err := rollbackMigrations(opts, pool, migration.RollbackOptions{To: "0"}); err != nil {
+			return err
+		}
+		return applyMigrations(opts, pool)
+	})
+}
+
+func interactiveCommand(opts *options, args []string) error {
+	flags := newFlagSet("interactive", opts)
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	return withPool(opts, func(pool *pgxpool.Pool) error {
+		return runInteractive(opts, pool)
+	})
+}
+
+func newMigrator(opts *options, pool *pgxpool.Pool) (*migration.Migrator, error) {
+	source, err := migration.NewSource(opts.source, rootx.Migrations, "migrations")
+	if err != nil {
+		return nil, usageErrorf("%v", err)
+	}
+	return migration.NewMigrator(pool, source).WithLockTimeout(opts.lockTimeout), nil
+}
+
+func applyMigrations(opts *options, pool *pgxpool.Pool) error {
+	fmt.Println("Applying migrations...")
+	migrator, err := newMigrator(opts, pool)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(context.Background())
+	return err
+}
+
+func rollbackMigrations(opts *options, pool *pgxpool.Pool, rollback migration.RollbackOptions) error {
+	fmt.Println("Rolling back migrations...")
+	migrator, err := newMigrator(opts, pool)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Rollback(context.Background(), rollback)
+	return err
+}
+
+// runSeeders runs the pending seeders of the environment, APP_ENV when env is empty
+func runSeeders(opts *options, pool *pgxpool.Pool, env string, run seed.RunOptions) error {
+	fmt.Println("Running seeders...")
+	source, err := migration.NewSource(opts.source, rootx.Seeds, "seeds")
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+
+	if env == "" {
+		env = viper.GetString("APP_ENV")
+	}
+	_, err = seed.NewRunner(pool, source, env).WithLockTimeout(opts.lockTimeout).Run(context.Background(), run)
+	return err
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/JubaerHossain/rootx/pkg/core/database/migration"
+	"github.com/JubaerHossain/rootx/pkg/core/validation"
+)
+
+// entityColumn is a table column derived from a struct field
+type entityColumn struct {
+	name string
+	// column is rendered the way the schema snapshot stores it, so both can be compared
+	column     *migration.Column
+	sqlType    string
+	primaryKey bool
+	serial     bool
+	unique     bool
+}
+
+// entityIndex is an index declared by the gorm tag of a struct field
+type entityIndex struct {
+	name   string
+	column string
+	unique bool
+}
+
+// entityTable is the table an entity struct maps to
+type entityTable struct {
+	name    string
+	columns []*entityColumn
+	indexes []*entityIndex
+}
+
+// entityParser reads entity structs from the Go sources of the module
+type entityParser struct {
+	module string
+	fset   *token.FileSet
+	files  map[string][]*ast.File
+}
+
+// newEntityParser creates a parser for the module of the go.mod in the working directory
+func newEntityParser() (*entityParser, error) {
+	file, err := os.Open("go.mod")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.mod, run the command from the project root: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return &entityParser{module: strings.TrimSpace(module), fset: token.NewFileSet(), files: map[string][]*ast.File{}}, nil
+		}
+	}
+	return nil, fmt.Errorf("go.mod has no module directive")
+}
+
+// parseEntity builds the table of an entity referenced as "<dir>.<Type>", e.g. "domain/entity.User".
+// The table name defaults to the plural snake case of the type name.
+func (p *entityParser) parseEntity(ref, tableName string) (*entityTable, error) {
+	dot := strings.LastIndex(ref, ".")
+	if dot <= 0 || dot == len(ref)-1 {
+		return nil, usageErrorf("--from-entity expects <package dir>.<Type>, e.g. domain/entity.User")
+	}
+	dir, typeName := filepath.Clean(ref[:dot]), ref[dot+1:]
+
+	expr, err := p.findType(dir, typeName)
+	if err != nil {
+		return nil, err
+	}
+	structType, ok := expr.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", ref)
+	}
+
+	if tableName == "" {
+		tableName = pluralize(snakeCase(typeName))
+	}
+	table := &entityTable{name: tableName}
+	if err := p.addFields(table, dir, structType); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	if len(table.columns) == 0 {
+		return nil, fmt.Errorf("%s has no columns", ref)
+	}
+	return table, nil
+}
+
+// addFields adds a column for every exported field of the struct, embedded structs included
+func (p *entityParser) addFields(table *entityTable, dir string, structType *ast.StructType) error {
+	for _, field := range structType.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			unquoted, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(unquoted)
+		}
+		gorm := parseGormTag(tag.Get("gorm"))
+		if _, skip := gorm["-"]; skip {
+			continue
+		}
+
+		if len(field.Names) == 0 {
+			// Embedded struct, its fields belong to the table
+			expr, embeddedDir, err := p.resolve(dir, p.fileAt(field.Pos()), field.Type)
+			if err != nil {
+				return err
+			}
+			embedded, ok := expr.(*ast.StructType)
+			if !ok {
+				return fmt.Errorf("embedded field %s is not a struct", types.ExprString(field.Type))
+			}
+			if err := p.addFields(table, embeddedDir, embedded); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			if err := p.addColumn(table, dir, field, name.Name, tag, gorm); err != nil {
+				return fmt.Errorf("field %s: %w", name.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// addColumn maps a struct field to a column and its indexes
+func (p *entityParser) addColumn(table *entityTable, dir string, field *ast.Field, fieldName string, tag reflect.StructTag, gorm map[string]string) error {
+	columnName := gorm["column"]
+	if columnName == "" {
+		columnName, _, _ = strings.Cut(tag.Get("json"), ",")
+	}
+	if columnName == "" || columnName == "-" {
+		columnName = snakeCase(fieldName)
+	}
+
+	column := &entityColumn{name: columnName, column: &migration.Column{}}
+	_, column.primaryKey = gorm["primarykey"]
+	_, column.unique = gorm["unique"]
+
+	goType, nullable, err := p.goType(dir, p.fileAt(field.Pos()), field.Type)
+	if err != nil {
+		return err
+	}
+
+	if sqlType := gorm["type"]; sqlType != "" {
+		column.sqlType = strings.ToUpper(sqlType)
+		column.column.Type = strings.ToLower(sqlType)
+	} else {
+		column.column.Type, err = columnType(goType, columnSize(tag.Get("validate"), gorm["size"]))
+		if err != nil {
+			return err
+		}
+		column.sqlType = ddlType(column.column.Type)
+	}
+
+	_, notNull := gorm["not null"]
+	column.column.Nullable = nullable && !notNull && !column.primaryKey
+
+	_, autoIncrement := gorm["autoincrement"]
+	switch {
+	case column.primaryKey && autoIncrement && (column.column.Type == "integer" || column.column.Type == "bigint"):
+		column.serial = true
+		column.sqlType = map[string]string{"integer": "SERIAL", "bigint": "BIGSERIAL"}[column.column.Type]
+		column.column.Default = fmt.Sprintf("nextval('%s_%s_seq'::regclass)", table.name, columnName)
+	case gorm["default"] != "":
+		column.column.Default = defaultExpr(gorm["default"], column.column.Type)
+	default:
+		_, autoCreate := gorm["autocreatetime"]
+		_, autoUpdate := gorm["autoupdatetime"]
+		if autoCreate || autoUpdate {
+			column.column.Default = "CURRENT_TIMESTAMP"
+		}
+	}
+	table.columns = append(table.columns, column)
+
+	// A unique column is already indexed by its constraint
+	for _, unique := range []bool{false, true} {
+		name, ok := gorm[map[bool]string{false: "index", true: "uniqueindex"}[unique]]
+		if !ok || column.primaryKey || (column.unique && !unique) {
+			continue
+		}
+		if name == "" {
+			name = fmt.Sprintf("idx_%s_%s", table.name, columnName)
+		}
+		table.indexes = append(table.indexes, &entityIndex{name: name, column: columnName, unique: unique})
+	}
+	return nil
+}
+
+// goType resolves a field type to a builtin type or time.Time, pointers are nullable
+func (p *entityParser) goType(dir string, file *ast.File, expr ast.Expr) (string, bool, error) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		goType, _, err := p.goType(dir, file, star.X)
+		return goType, true, err
+	}
+
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if isBuiltin(t.Name) {
+			return t.Name, false, nil
+		}
+	case *ast.SelectorExpr:
+		if path := importPath(file, t); path == "time" && t.Sel.Name == "Time" {
+			return "time.Time", false, nil
+		}
+	}
+
+	resolved, resolvedDir, err := p.resolve(dir, file, expr)
+	if err != nil {
+		return "", false, err
+	}
+	if resolved == expr {
+		return "", false, fmt.Errorf("unsupported type %s, set the column type with gorm:\"type:...\" or skip it with gorm:\"-\"", types.ExprString(expr))
+	}
+	return p.goType(resolvedDir, p.fileAt(resolved.Pos()), resolved)
+}
+
+// resolve returns the definition of a named type of the module, or expr itself when it is not one
+func (p *entityParser) resolve(dir string, file *ast.File, expr ast.Expr) (ast.Expr, string, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if isBuiltin(t.Name) {
+			return expr, dir, nil
+		}
+		definition, err := p.findType(dir, t.Name)
+		return definition, dir, err
+	case *ast.SelectorExpr:
+		path := importPath(file, t)
+		relative, ok := strings.CutPrefix(path, p.module+"/")
+		if !ok {
+			return expr, dir, nil
+		}
+		definition, err := p.findType(filepath.FromSlash(relative), t.Sel.Name)
+		return definition, filepath.FromSlash(relative), err
+	}
+	return expr, dir, nil
+}
+
+// findType returns the definition of a type declared in the package of dir
+func (p *entityParser) findType(dir, name string) (ast.Expr, error) {
+	files, err := p.parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if typeSpec := spec.(*ast.TypeSpec); typeSpec.Name.Name == name {
+					return typeSpec.Type, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("type %s not found in %s", name, dir)
+}
+
+// parseDir parses the Go files of a package, tests excluded
+func (p *entityParser) parseDir(dir string) ([]*ast.File, error) {
+	if files, ok := p.files[dir]; ok {
+		return files, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	files := []*ast.File{}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(p.fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		files = append(files, file)
+	}
+	p.files[dir] = files
+	return files, nil
+}
+
+// fileAt returns the parsed file containing a position, needed to resolve its imports
+func (p *entityParser) fileAt(pos token.Pos) *ast.File {
+	for _, files := range p.files {
+		for _, file := range files {
+			if file.FileStart <= pos && pos <= file.FileEnd {
+				return file
+			}
+		}
+	}
+	return nil
+}
+
+// importPath returns the import path of the package qualifying a selector, e.g. "time" for time.Time
+func importPath(file *ast.File, selector *ast.SelectorExpr) string {
+	pkg, ok := selector.X.(*ast.Ident)
+	if !ok || file == nil {
+		return ""
+	}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == pkg.Name {
+			return path
+		}
+	}
+	return ""
+}
+
+// parseGormTag splits a gorm tag into its settings, keys are lower case, e.g. "index;default:user"
+func parseGormTag(tag string) map[string]string {
+	settings := map[string]string{}
+	for _, setting := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(setting, ":")
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			settings[key] = strings.TrimSpace(value)
+		}
+	}
+	return settings
+}
+
+// columnSize returns the length of a string column from the gorm size, or the validate max, the
+// longest oneof option or the longest value of a custom tag such as phone or role, 0 when unbounded
+func columnSize(validate, size string) int {
+	if n, err := strconv.Atoi(size); err == nil {
+		return n
+	}
+
+	longest := 0
+	for _, rule := range strings.Split(validate, ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "max", "len":
+			if n, err := strconv.Atoi(value); err == nil {
+				return n
+			}
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				longest = max(longest, len(option))
+			}
+		default:
+			longest = max(longest, validation.TagMaxLength(name))
+		}
+	}
+	return longest
+}
+
+// columnType maps a Go type to its Postgres type, spelled the way format_type renders it
+func columnType(goType string, size int) (string, error) {
+	switch goType {
+	case "string":
+		if size > 0 {
+			return fmt.Sprintf("character varying(%d)", size), nil
+		}
+		return "text", nil
+	case "bool":
+		return "boolean", nil
+	case "int8", "int16", "uint8", "byte":
+		return "smallint", nil
+	case "int", "int32", "rune", "uint", "uint16", "uint32":
+		return "integer", nil
+	case "int64", "uint64":
+		return "bigint", nil
+	case "float32":
+		return "real", nil
+	case "float64":
+		return "double precision", nil
+	case "time.Time":
+		return "timestamp without time zone", nil
+	}
+	return "", fmt.Errorf("unsupported type %s, set the column type with gorm:\"type:...\"", goType)
+}
+
+// ddlType spells a column type the way the migrations of this project do
+func ddlType(columnType string) string {
+	if size, ok := strings.CutPrefix(columnType, "character varying"); ok {
+		return "VARCHAR" + size
+	}
+	if columnType == "timestamp without time zone" {
+		return "TIMESTAMP"
+	}
+	return strings.ToUpper(columnType)
+}
+
+// defaultExpr renders a gorm default the way pg_get_expr does, quoting and casting string literals
+func defaultExpr(value, columnType string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	switch strings.ToLower(value) {
+	case "true", "false", "null":
+		return strings.ToLower(value)
+	case "current_timestamp", "now()":
+		return "CURRENT_TIMESTAMP"
+	}
+	if strings.Contains(value, "(") {
+		return value
+	}
+
+	literal := "'" + strings.ReplaceAll(strings.Trim(value, "'"), "'", "''") + "'"
+	if strings.HasPrefix(columnType, "character varying") {
+		return literal + "::character varying"
+	}
+	return literal + "::" + columnType
+}
+
+// isBuiltin reports whether name is a predeclared Go type
+func isBuiltin(name string) bool {
+	switch name {
+	case "string", "bool", "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64", "byte", "rune":
+		return true
+	}
+	return false
+}
+
+// snakeCase converts a Go name to snake case, e.g. CreatedAt to created_at and UserID to user_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			previousLower := i > 0 && unicode.IsLower(runes[i-1])
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if previousLower || nextLower {
+				builder.WriteByte('_')
+			}
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
+
+// pluralize returns the English plural of a table name, e.g. user to users and category to categories
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
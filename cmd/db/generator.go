@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/database/migration"
+)
+
+func createMigrationFile(name string) error {
+	fmt.Println("Creating migration file...")
+	fmt.Printf("Migration name: %s\n", name)
+
+	up := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", name) +
+		"    id SERIAL PRIMARY KEY,\n" +
+		"    name VARCHAR(100) NOT NULL,\n" +
+		"    description TEXT NOT NULL,\n" +
+		"    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
+		");\n\n" +
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_name_idx ON %s (name);\n", name, name) // Modify column_name with the actual column name
+	down := fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", name)
+
+	return writeMigrationFile(name, fmt.Sprintf("-- Migration %s\n", name), up, down)
+}
+
+// createMigrationFromEntity generates the migration of an entity struct, e.g. "domain/entity.User".
+// It creates the table when the schema snapshot does not have it yet and alters it otherwise.
+func createMigrationFromEntity(ref, name, tableName, snapshotPath string) error {
+	parser, err := newEntityParser()
+	if err != nil {
+		return err
+	}
+	table, err := parser.parseEntity(ref, tableName)
+	if err != nil {
+		return err
+	}
+
+	// Without the snapshot an existing table would look new, and the down section of its
+	// CREATE would drop the live table
+	if _, err := os.Stat(snapshotPath); err != nil {
+		return fmt.Errorf("no schema snapshot at %s, run \"db schema:dump\" against a migrated database first", snapshotPath)
+	}
+	schema, err := migration.LoadSnapshot(snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	var up, down string
+	existing, exists := schema.Tables[table.name]
+	if !exists {
+		creator, err := tableCreatedBy("migrations", table.name)
+		if err != nil {
+			return err
+		}
+		if creator != "" {
+			return fmt.Errorf("table %s is created by %s but missing from %s, refresh the snapshot with \"db schema:dump\"", table.name, creator, snapshotPath)
+		}
+		up, down = createTableSQL(table)
+		if name == "" {
+			name = fmt.Sprintf("create_%s_table", table.name)
+		}
+	} else {
+		up, down = alterTableSQL(table, existing)
+		if up == "" {
+			fmt.Printf("Table %s already matches %s, no migration created\n", table.name, ref)
+			return nil
+		}
+		if name == "" {
+			name = fmt.Sprintf("alter_%s_table", table.name)
+		}
+	}
+
+	fmt.Println("Creating migration file...")
+	fmt.Printf("Migration name: %s\n", name)
+	return writeMigrationFile(name, fmt.Sprintf("-- Migration %s\n-- Generated from %s\n", name, ref), up, down)
+}
+
+// createTablePattern matches the CREATE TABLE statement of a table, %s being its quoted name
+const createTablePattern = `(?i)CREATE\s+TABLE\s+(IF\s+NOT\s+EXISTS\s+)?("?public"?\.)?"?%s"?\s*\(`
+
+// tableCreatedBy returns the migration file of dir creating the table, "" when there is none
+func tableCreatedBy(dir, table string) (string, error) {
+	pattern := regexp.MustCompile(fmt.Sprintf(createTablePattern, regexp.QuoteMeta(table)))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" || strings.HasSuffix(entry.Name(), migration.DownSuffix) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		up, _ := migration.Parse(string(content))
+		if pattern.MatchString(up) {
+			return entry.Name(), nil
+		}
+	}
+	return "", nil
+}
+
+// writeMigrationFile writes a timestamped migration with its up and down sections
+func writeMigrationFile(name, header, up, down string) error {
+	timestamp := time.Now().Format("2006_01_02_150405")
+	filename := filepath.Join("migrations", fmt.Sprintf("%s_%s.sql", timestamp, name))
+	content := header + "\n" +
+		"-- +up\n" + up + "\n" +
+		"-- +down\n" + down
+
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to create migration file: %w", err)
+	}
+
+	fmt.Printf("Migration file created: %s\n", filename)
+	return nil
+}
+
+// createTableSQL renders the statements creating and dropping the table of an entity
+func createTableSQL(table *entityTable) (string, string) {
+	definitions := []string{}
+	for _, column := range table.columns {
+		definitions = append(definitions, "    "+columnDefinition(column))
+	}
+
+	// No IF NOT EXISTS: the down section drops the table, so the migration must fail rather than adopt a table it did not create
+	up := fmt.Sprintf("CREATE TABLE %s (\n%s\n);\n", table.name, strings.Join(definitions, ",\n"))
+	if len(table.indexes) > 0 {
+		up += "\n"
+		for _, index := range table.indexes {
+			up += createIndexSQL(table.name, index)
+		}
+	}
+	return up, fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", table.name)
+}
+
+// alterTableSQL renders the statements bringing an existing table in line with its entity and back.
+// Columns and indexes that the entity no longer declares are only reported in comments since
+// dropping them loses data.
+func alterTableSQL(table *entityTable, existing *migration.Table) (string, string) {
+	up, down := []string{}, []string{}
+	declared := map[string]bool{table.name + "_pkey": true}
+
+	for _, column := range table.columns {
+		current, exists := existing.Columns[column.name]
+		if !exists {
+			statement := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table.name, columnDefinition(column))
+			if !column.column.Nullable && column.column.Default == "" {
+				statement = "-- The table must be empty or the column needs a default\n" + statement
+			}
+			up = append(up, statement)
+			down = append(down, fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s;", table.name, column.name))
+			if column.unique {
+				declared[fmt.Sprintf("%s_%s_key", table.name, column.name)] = true
+			}
+			continue
+		}
+
+		alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", table.name, column.name)
+		if current.Type != column.column.Type {
+			up = append(up, fmt.Sprintf("%s TYPE %s USING %s::%s;", alter, ddlType(column.column.Type), column.name, ddlType(column.column.Type)))
+			down = append(down, fmt.Sprintf("%s TYPE %s USING %s::%s;", alter, ddlType(current.Type), column.name, ddlType(current.Type)))
+		}
+		if current.Nullable != column.column.Nullable {
+			up = append(up, alter+nullability(column.column.Nullable))
+			down = append(down, alter+nullability(current.Nullable))
+		}
+		if current.Default != column.column.Default && !column.serial {
+			up = append(up, alter+defaultClause(column.column.Default))
+			down = append(down, alter+defaultClause(current.Default))
+		}
+		if column.unique {
+			constraint := fmt.Sprintf("%s_%s_key", table.name, column.name)
+			declared[constraint] = true
+			if _, ok := existing.Indexes[constraint]; !ok {
+				up = append(up, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s);", table.name, constraint, column.name))
+				down = append(down, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", table.name, constraint))
+			}
+		}
+	}
+
+	for _, index := range table.indexes {
+		declared[index.name] = true
+		if _, ok := existing.Indexes[index.name]; !ok {
+			up = append(up, strings.TrimSuffix(createIndexSQL(table.name, index), "\n"))
+			down = append(down, fmt.Sprintf("DROP INDEX IF EXISTS %s;", index.name))
+		}
+	}
+
+	if len(up) == 0 {
+		return "", ""
+	}
+
+	columns := map[string]bool{}
+	for _, column := range table.columns {
+		columns[column.name] = true
+	}
+	notes := []string{}
+	for _, name := range sortedNames(existing.Columns) {
+		if !columns[name] {
+			notes = append(notes, fmt.Sprintf("-- Column %s.%s is not declared by the entity, drop it by hand if it is unused:\n-- ALTER TABLE %s DROP COLUMN %s;", table.name, name, table.name, name))
+		}
+	}
+	for _, name := range sortedNames(existing.Indexes) {
+		if !declared[name] {
+			notes = append(notes, fmt.Sprintf("-- Index %s is not declared by the entity:\n-- DROP INDEX %s;", name, name))
+		}
+	}
+
+	// Undo in the reverse order
+	for i, j := 0, len(down)-1; i < j; i, j = i+1, j-1 {
+		down[i], down[j] = down[j], down[i]
+	}
+
+	return strings.Join(append(up, notes...), "\n") + "\n", strings.Join(down, "\n") + "\n"
+}
+
+// columnDefinition renders a column as it appears in CREATE TABLE and ADD COLUMN
+func columnDefinition(column *entityColumn) string {
+	definition := column.name + " " + column.sqlType
+	if column.primaryKey {
+		return definition + " PRIMARY KEY"
+	}
+	if !column.column.Nullable {
+		definition += " NOT NULL"
+	}
+	if column.unique {
+		definition += " UNIQUE"
+	}
+	if column.column.Default != "" {
+		definition += " DEFAULT " + sqlDefault(column.column.Default)
+	}
+	return definition
+}
+
+// createIndexSQL renders the statement creating an index
+func createIndexSQL(tableName string, index *entityIndex) string {
+	kind := "INDEX"
+	if index.unique {
+		kind = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("CREATE %s IF NOT EXISTS %s ON %s (%s);\n", kind, index.name, tableName, index.column)
+}
+
+// sqlDefault drops the cast pg_get_expr adds to string literals, e.g. 'user'::character varying
+func sqlDefault(expr string) string {
+	if strings.HasPrefix(expr, "'") {
+		if end := strings.LastIndex(expr, "'::"); end > 0 {
+			return expr[:end+1]
+		}
+	}
+	return expr
+}
+
+func nullability(nullable bool) string {
+	if nullable {
+		return " DROP NOT NULL;"
+	}
+	return " SET NOT NULL;"
+}
+
+func defaultClause(expr string) string {
+	if expr == "" {
+		return " DROP DEFAULT;"
+	}
+	return " SET DEFAULT " + sqlDefault(expr) + ";"
+}
+
+// sortedNames returns the keys of a map in order
+func sortedNames[V any](items map[string]V) []string {
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func createSeedFile(tableName string) error {
+	fmt.Println("Creating seed file...")
+	fmt.Printf("Seed table name: %s\n", tableName)
+
+	timestamp := time.Now().Format("2006_01_02_150405")
+	filename := filepath.Join("seeds", fmt.Sprintf("%s_%s_seeder.sql", timestamp, tableName))
+	content := fmt.Sprintf("-- Seeder for table %s\n\n", tableName) +
+		fmt.Sprintf("INSERT INTO %s (name, description, created_at, updated_at) VALUES\n", tableName) +
+		"    ('Value1', 'Description 1', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),\n" +
+		"    ('Value2', 'Description 2', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);\n"
+
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to create seed file: %w", err)
+	}
+
+	fmt.Printf("Seed file created: %s\n", filename)
+	return nil
+}
+
+func createMigrationFileWithSeeder(name string) error {
+	if err := createMigrationFile(name); err != nil {
+		return err
+	}
+
+	tableName := strings.ToLower(name)
+	if err := createSeedFile(tableName); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/JubaerHossain/rootx/pkg/core/database/migration"
+	"github.com/JubaerHossain/rootx/pkg/core/database/seed"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// runInteractive shows the command menu until the user exits or the input is closed
+func runInteractive(opts *options, pool *pgxpool.Pool) error {
+	for {
+		// Display the command options
+		showCommandOptions()
+
+		// Prompt user to select a command and perform the action
+		command, err := getUserInput("Enter the command number: ")
+		if err != nil {
+			return inputClosed(err)
+		}
+		switch command {
+		case "1":
+			migrationName, err := getUserInput("Enter migration name: ")
+			if err != nil {
+				return inputClosed(err)
+			}
+			if err := createMigrationFile(migrationName); err != nil {
+				fmt.Printf("\x1b[31mError creating migration: %v\x1b[0m\n", err)
+			}
+		case "2":
+			migrationName, err := getUserInput("Enter migration name: ")
+			if err != nil {
+				return inputClosed(err)
+			}
+			if err := createMigrationFileWithSeeder(migrationName); err != nil {
+				fmt.Printf("\x1b[31mError creating migration with seeder: %v\x1b[0m\n", err)
+			}
+		case "3":
+			if err := applyMigrations(opts, pool); err != nil {
+				fmt.Printf("\x1b[31mError applying migrations: %v\x1b[0m\n", err)
+			}
+		case "4":
+			if err := runSeeders(opts, pool, "", seed.RunOptions{}); err != nil {
+				fmt.Printf("\x1b[31mError running seeders. Please migrate before: %v\x1b[0m\n", err)
+			}
+		case "5":
+			// Asks first like migrate down, unless --yes was given
+			if err := confirm(opts, "roll back the last migration"); err != nil {
+				fmt.Printf("\x1b[31mNot rolling back: %v\x1b[0m\n", err)
+				continue
+			}
+			if err := rollbackMigrations(opts, pool, migration.RollbackOptions{Steps: 1}); err != nil {
+				fmt.Printf("\x1b[31mError rolling back migration: %v\x1b[0m\n", err)
+			}
+		case "0":
+			fmt.Println("Exiting...")
+			return nil
+		default:
+			fmt.Println("Invalid command")
+		}
+	}
+}
+
+// inputClosed ends the menu quietly at the end of the input, e.g. with </dev/null
+func inputClosed(err error) error {
+	if errors.Is(err, io.EOF) {
+		fmt.Println()
+		return nil
+	}
+	return fmt.Errorf("failed to read input: %w", err)
+}
+
+func showCommandOptions() {
+	fmt.Print(`
+   ___  ____  ____  _______  __
+  / _ \/ __ \/ __ \/_  __/ |/_/
+ / , _/ /_/ / /_/ / / / _>  <
+/_/|_|\____/\____/ /_/ /_/|_|
+
+`)
+	fmt.Println("\x1b[35mSelect a command:\x1b[0m")
+	fmt.Println("\x1b[32m1. Create Migration\x1b[0m")
+	fmt.Println("\x1b[37m2. Create Migration with Seeder\x1b[0m")
+	fmt.Println("\x1b[33m3. Apply Migrations\x1b[0m")
+	fmt.Println("\x1b[34m4. Run Seeders\x1b[0m")
+	fmt.Println("\x1b[36m5. Rollback Last Migration\x1b[0m")
+	fmt.Println("\x1b[31m0. Exit\x1b[0m")
+}
@@ -3,89 +3,199 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/JubaerHossain/rootx/pkg/core/database/migration"
+	_ "github.com/JubaerHossain/rootx/pkg/core/database/seed/data"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/spf13/viper"
 )
 
+// Exit codes returned by the CLI
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// defaultEnvFile is read when --env-file is not given, it may be absent when the environment is set
+const defaultEnvFile = ".env"
+
+// options holds the flags shared by every command
+type options struct {
+	envFile     string
+	yes         bool
+	lockTimeout time.Duration
+	source      string
+}
+
+// usageError reports a malformed command line, it exits with exitUsage
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
 func main() {
-	// Connect to the database
-	pool, err := connectDB()
-	if err != nil {
-		log.Fatalf("Failed to connect to the database: %v", err)
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses the command line, executes the selected command and returns the exit code
+func run(args []string) int {
+	opts := &options{envFile: defaultEnvFile, lockTimeout: migration.DefaultLockTimeout, source: migration.SourceDisk}
+	global := newFlagSet("db", opts)
+	global.Usage = printUsage
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
 	}
-	defer pool.Close()
 
-	// Display the command options
-	showCommandOptions()
+	args = global.Args()
+	if len(args) == 0 {
+		printUsage()
+		return exitUsage
+	}
 
-	// Prompt user to select a command
-	command := getUserInput("Enter the command number: ")
+	err := dispatch(args[0], args[1:], opts)
+	if err == nil {
+		return exitOK
+	}
 
-	// Perform action based on the selected command
-	switch command {
-	case "1":
-		migrationName := getUserInput("Enter migration name: ")
-		if err := createMigrationFile(migrationName); err != nil {
-			fmt.Println("\x1b[31mError creating migration\x1b[0m")
-			showCommandOptions()
-			getUserInput("Enter the command number: ")
-		}
-	case "2":
-		migrationName := getUserInput("Enter migration name: ")
-		if err := createMigrationFileWithSeeder(migrationName); err != nil {
-			fmt.Println("\x1b[31mError creating migration with seeder\x1b[0m")
-			showCommandOptions()
-			getUserInput("Enter the command number: ")
-		}
-	case "3":
-		if err := applyMigrations(pool); err != nil {
-			fmt.Println("\x1b[31mError applying migrations\x1b[0m")
-			showCommandOptions()
-			getUserInput("Enter the command number: ")
-		}
-	case "4":
-		if err := runSeeders(pool); err != nil {
-			fmt.Println("\x1b[31mError running seeders . Please migrate before \x1b[0m")
-			showCommandOptions()
-			getUserInput("Enter the command number: ")
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		if usageErr.message != "" {
+			fmt.Fprintf(os.Stderr, "\x1b[31m%s\x1b[0m\n\n", usageErr.message)
+			printUsage()
 		}
-	case "0":
-		fmt.Println("Exiting...")
-		os.Exit(0)
+		return exitUsage
+	}
+
+	fmt.Fprintf(os.Stderr, "\x1b[31mError: %v\x1b[0m\n", err)
+	return exitError
+}
 
+// dispatch runs the command with the given name
+func dispatch(name string, args []string, opts *options) error {
+	switch name {
+	case "migrate":
+		return migrateCommand(opts, args)
+	case "rollback":
+		return migrateDown(opts, args)
+	case "make:migration":
+		return makeMigrationCommand(opts, args)
+	case "schema:dump":
+		return schemaDumpCommand(opts, args)
+	case "seed":
+		return seedCommand(opts, args)
+	case "reset":
+		return resetCommand(opts, args)
+	case "interactive":
+		return interactiveCommand(opts, args)
+	case "help":
+		printUsage()
+		return nil
 	default:
-		fmt.Println("Invalid command")
+		return usageErrorf("unknown command %q", name)
 	}
-
-	showCommandOptions()
-	// Prompt user to select a command
-	getUserInput("Enter the command number: ")
 }
 
-func showCommandOptions() {
-	fmt.Println(`
-   ___  ____  ____  _______  __
-  / _ \/ __ \/ __ \/_  __/ |/_/
- / , _/ /_/ / /_/ / / / _>  <  
-/_/|_|\____/\____/ /_/ /_/|_|  
-								 
+func printUsage() {
+	fmt.Fprint(os.Stderr, `Usage: db [--env-file path] [--yes] [--lock-timeout 1m] [--source disk|embed] <command> [arguments]
+
+Commands:
+  migrate up                      apply all pending migrations
+  migrate down [--steps N|--to V] roll back migrations (destructive)
+  migrate status [--drift]        list migrations with their state, optionally with the schema drift
+  migrate redo [--steps N]        roll back and re-apply the last migrations (destructive)
+  rollback [--steps N|--to V]     alias of "migrate down"
+  make:migration <name> [--seeder] create a migration file, optionally with a seeder
+  make:migration [name] --from-entity domain/entity.User [--table users]
+                                  generate the CREATE or ALTER TABLE of an entity struct against the snapshot
+  schema:dump [--snapshot path]   write the live schema to the committed snapshot
+  seed [--name seeder] [--count n] [--force] [--env dev|test|prod]
+                                  run the SQL and Go seeders that have not run yet
+  reset                           roll back every migration and apply them again (destructive)
+  interactive                     open the interactive menu
+
+Flags:
+  --env-file path                 environment file to read the database settings from (default ".env")
+  --yes                           confirm destructive operations without prompting
+  --lock-timeout duration         how long to wait for another instance that is migrating (default 1m)
+  --source disk|embed             read migrations and seeds from disk or from the embedded copies (default disk)
 `)
-	fmt.Println("\x1b[35mSelect a command:\x1b[0m")
-	fmt.Println("\x1b[32m1. Create Migration\x1b[0m")
-	fmt.Println("\x1b[37m2. Create Migration with Seeder\x1b[0m")
-	fmt.Println("\x1b[33m3. Apply Migrations\x1b[0m")
-	fmt.Println("\x1b[34m4. Run Seeders\x1b[0m")
-	fmt.Println("\x1b[31m0. Exit\x1b[0m")
 }
 
-func getUserInput(prompt string) string {
+// newFlagSet creates a flag set that also accepts the shared flags
+func newFlagSet(name string, opts *options) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&opts.envFile, "env-file", opts.envFile, "environment file to read the database settings from")
+	flags.BoolVar(&opts.yes, "yes", opts.yes, "confirm destructive operations without prompting")
+	flags.DurationVar(&opts.lockTimeout, "lock-timeout", opts.lockTimeout, "how long to wait for another instance that is migrating")
+	flags.StringVar(&opts.source, "source", opts.source, "read the SQL files from disk or from the files embedded in the binary (disk|embed)")
+	return flags
+}
+
+// parseFlags parses flags that may appear before or after positional arguments
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &usageError{}
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// confirm asks before a destructive operation unless --yes was given
+func confirm(opts *options, action string) error {
+	if opts.yes {
+		return nil
+	}
+
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return usageErrorf("refusing to %s without --yes", action)
+	}
+
+	answer, err := getUserInput(fmt.Sprintf("Are you sure you want to %s? [y/N]: ", action))
+	if err != nil || (answer != "y" && answer != "yes") {
+		return errors.New("aborted")
+	}
+	return nil
+}
+
+// stdin is shared by every prompt, a reader per prompt would drop the input another one buffered
+var stdin = bufio.NewReader(os.Stdin)
+
+// getUserInput prints the prompt and reads a line, io.EOF once the input is closed
+func getUserInput(prompt string) (string, error) {
 	// ANSI escape code for green color
 	green := "\033[32m"
 	// ANSI escape code to reset color
@@ -94,16 +204,34 @@ func getUserInput(prompt string) string {
 	// Print prompt in green color
 	fmt.Print(green + prompt + reset)
 
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	return strings.TrimSpace(input)
+	input, err := stdin.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || input == "") {
+		// Keep a last line without a newline, the next read returns io.EOF
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
 }
 
-func connectDB() (*pgxpool.Pool, error) {
-	// Load configuration from environment file
-	viper.SetConfigFile(".env")
+// withPool connects to the database, runs fn and closes the connection
+func withPool(opts *options, fn func(pool *pgxpool.Pool) error) error {
+	pool, err := connectDB(opts.envFile)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the database: %w", err)
+	}
+	defer pool.Close()
+
+	return fn(pool)
+}
+
+func connectDB(envFile string) (*pgxpool.Pool, error) {
+	// Load configuration from the environment file, environment variables take precedence
+	viper.SetConfigFile(envFile)
+	viper.SetConfigType("env")
+	viper.AutomaticEnv()
 	if err := viper.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		if _, statErr := os.Stat(envFile); !os.IsNotExist(statErr) || envFile != defaultEnvFile {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
 	}
 
 	// Retrieve database configuration
@@ -127,96 +255,3 @@ func connectDB() (*pgxpool.Pool, error) {
 
 	return pgxpool.NewWithConfig(context.Background(), config)
 }
-
-func createMigrationFile(name string) error {
-	fmt.Println("Creating migration file...")
-	fmt.Printf("Migration name: %s\n", name)
-
-	timestamp := time.Now().Format("2006_01_02_150405")
-	filename := filepath.Join("migrations", fmt.Sprintf("%s_%s.sql", timestamp, name))
-	content := fmt.Sprintf("-- Migration %s\n\n", name) +
-		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", name) +
-		"    id SERIAL PRIMARY KEY,\n" +
-		"    name VARCHAR(100) NOT NULL,\n" +
-		"    description TEXT NOT NULL,\n" +
-		"    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
-		"    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
-		");\n\n" +
-		fmt.Sprintf("CREATE INDEX ON %s (name);\n", name) // Modify column_name with the actual column name
-
-	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to create migration file: %w", err)
-	}
-
-	fmt.Printf("Migration file created: %s\n", filename)
-	return nil
-}
-
-func createSeedFile(tableName string) error {
-	fmt.Println("Creating seed file...")
-	fmt.Printf("Seed table name: %s\n", tableName)
-
-	timestamp := time.Now().Format("2006_01_02_150405")
-	filename := filepath.Join("seeds", fmt.Sprintf("%s_%s_seeder.sql", timestamp, tableName))
-	content := fmt.Sprintf("-- Seeder for table %s\n\n", tableName) +
-		fmt.Sprintf("INSERT INTO %s (name, description, created_at, updated_at) VALUES\n", tableName) +
-		"    ('Value1', 'Description 1', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),\n" +
-		"    ('Value2', 'Description 2', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);\n"
-
-	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to create seed file: %w", err)
-	}
-
-	fmt.Printf("Seed file created: %s\n", filename)
-	return nil
-}
-
-func createMigrationFileWithSeeder(name string) error {
-	if err := createMigrationFile(name); err != nil {
-		return err
-	}
-
-	tableName := strings.ToLower(name)
-	if err := createSeedFile(tableName); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func applyMigrations(pool *pgxpool.Pool) error {
-	fmt.Println("Applying migrations...")
-	return executeScriptsInDirectory(pool, "migrations")
-}
-
-func runSeeders(pool *pgxpool.Pool) error {
-	fmt.Println("Running seeders...")
-	return executeScriptsInDirectory(pool, "seeds")
-}
-
-func executeScriptsInDirectory(pool *pgxpool.Pool, directory string) error {
-	entries, err := os.ReadDir(directory)
-	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		filePath := filepath.Join(directory, entry.Name())
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", filePath, err)
-		}
-
-		_, err = pool.Exec(context.Background(), string(content))
-		if err != nil {
-			return fmt.Errorf("failed to execute file %s: %w", filePath, err)
-		}
-	}
-
-	log.Printf("%s files executed successfully", directory)
-	return nil
-}
@@ -9,6 +9,8 @@ import (
 	"github.com/JubaerHossain/rootx/domain/infrastructure/transport/routes/api"
 	"github.com/JubaerHossain/rootx/domain/infrastructure/transport/routes/web"
 	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/auth"
+	_ "github.com/JubaerHossain/rootx/pkg/core/database/seed/data"
 	"github.com/JubaerHossain/rootx/pkg/core/health"
 	"github.com/JubaerHossain/rootx/pkg/core/middleware"
 	"github.com/JubaerHossain/rootx/pkg/core/monitor"
@@ -21,6 +23,10 @@ import (
 // @description     This is a starter API for Golang projects
 // @host            localhost:3021
 // @BasePath        /api
+// @securityDefinitions.apikey BearerAuth
+// @in                         header
+// @name                       Authorization
+// @description                Type "Bearer" followed by a space and the token returned by /auth/login
 func main() {
 	// Initialize the application
 	application, err := app.StartApp()
@@ -58,6 +64,9 @@ func setupRoutes(application *app.App) http.Handler {
 	// Register Swagger routes
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
 
+	// Register the public keys verifying the access tokens
+	mux.Handle("/.well-known/jwks.json", auth.JWKSHandler())
+
 	// Register health check endpoint
 	mux.Handle("/health", middleware.LoggingMiddleware(http.HandlerFunc(health.HealthCheckHandler())))
 
@@ -71,5 +80,8 @@ func setupRoutes(application *app.App) http.Handler {
 		utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{"message": "Welcome to the API"})
 	}))))
 
-	return middleware.PrometheusMiddleware(mux, monitor.RequestsTotal(), monitor.RequestDuration())
+	// Every request gets an ID, and its errors are written as problem details when asked for
+	handler := middleware.RequestID(middleware.ProblemDetails(mux))
+
+	return middleware.PrometheusMiddleware(handler, monitor.RequestsTotal(), monitor.RequestDuration())
 }
@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/auth"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+)
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token of the same family.
+// The token is used up: presenting it again means it leaked, so the whole family is revoked and the
+// user has to log in again. The family is revoked as well when the user is no longer active.
+func (c *App) Refresh(ctx context.Context, refreshToken string, device entity.Device) (*entity.LoginUserResponse, error) {
+	var response *entity.LoginUserResponse
+	// rejected is returned once the transaction revoking the family has committed
+	var rejected error
+	err := c.app.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := c.refreshTokens.GetByHash(ctx, auth.HashRefreshToken(refreshToken))
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case current.RevokedAt != nil:
+			return entity.ErrInvalidRefreshToken
+		case current.UsedAt != nil:
+			rejected = entity.ErrRefreshTokenReused
+			return c.refreshTokens.RevokeFamily(ctx, current.FamilyID)
+		case !time.Now().UTC().Before(current.ExpiresAt):
+			return entity.ErrRefreshTokenExpired
+		}
+
+		user, err := c.repo.GetUserByID(ctx, current.UserID)
+		if err != nil {
+			return err
+		}
+		if user.Status != coreEntity.Active {
+			rejected = entity.ErrUserNotActive
+			return c.refreshTokens.RevokeFamily(ctx, current.FamilyID)
+		}
+		if err := c.refreshTokens.MarkUsed(ctx, current.ID); err != nil {
+			return err
+		}
+
+		token, expiresAt, err := auth.CreateToken(user.AuthUser(), user.TokenVersion)
+		if err != nil {
+			return err
+		}
+		response = &entity.LoginUserResponse{
+			ID:             user.ID,
+			Name:           user.Name,
+			Phone:          user.Phone,
+			Status:         user.Status,
+			Token:          token,
+			TokenExpiresAt: expiresAt,
+		}
+		return c.issueRefreshToken(ctx, response, current.FamilyID, device)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	return response, nil
+}
+
+// Logout revokes the access token of the request and, when it is given, the family of the refresh token.
+// The access token is revoked first so a logout that cannot revoke it changes nothing.
+func (c *App) Logout(ctx context.Context, token *auth.Token, refreshToken string) error {
+	if err := auth.RevokeToken(ctx, c.app.Cache, token); err != nil {
+		return err
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	return c.app.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := c.refreshTokens.GetByHash(ctx, auth.HashRefreshToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		// Only the owner can end a session
+		if current.UserID != token.User.ID {
+			return entity.ErrInvalidRefreshToken
+		}
+		return c.refreshTokens.RevokeFamily(ctx, current.FamilyID)
+	})
+}
+
+// LogoutAll ends the sessions of a user on every device
+func (c *App) LogoutAll(ctx context.Context, userID uint) error {
+	return c.app.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		return c.revokeSessions(ctx, userID)
+	})
+}
+
+// revokeSessions invalidates the access tokens and revokes the refresh tokens of a user, in the transaction of ctx
+func (c *App) revokeSessions(ctx context.Context, userID uint) error {
+	if err := c.refreshTokens.RevokeUser(ctx, userID); err != nil {
+		return err
+	}
+	return c.repo.RevokeTokens(ctx, userID)
+}
+
+// TokenVersion returns the token version of a user, so App is the auth.TokenVersions of the authenticator
+func (c *App) TokenVersion(ctx context.Context, userID uint) (int, error) {
+	return c.repo.TokenVersion(ctx, userID)
+}
+
+// RolePermissions returns the permissions granted to a role, so App is the auth.RolePermissions of the authorizer
+func (c *App) RolePermissions(ctx context.Context, role coreEntity.Role) ([]coreEntity.Permission, error) {
+	return c.permissions.RolePermissions(ctx, role)
+}
+
+// issueRefreshToken stores a new refresh token of the family and adds it to the response
+func (c *App) issueRefreshToken(ctx context.Context, response *entity.LoginUserResponse, familyID string, device entity.Device) error {
+	token, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	refreshToken := &entity.RefreshToken{
+		UserID:    response.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		UserAgent: truncate(device.UserAgent, 255),
+		IPAddress: truncate(device.IPAddress, 45),
+		// expires_at has no time zone, it is stored and compared in UTC
+		ExpiresAt: time.Now().UTC().Add(auth.RefreshTokenTTL()),
+	}
+	if err := c.refreshTokens.Create(ctx, refreshToken); err != nil {
+		return err
+	}
+
+	response.RefreshToken = token
+	response.RefreshTokenExpiresAt = refreshToken.ExpiresAt
+	return nil
+}
+
+// truncate cuts s to at most n bytes without splitting a character, to fit a VARCHAR column
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
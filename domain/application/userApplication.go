@@ -3,33 +3,36 @@
 package application
 
 import (
-	"fmt"
-	"net/http"
-	"strconv"
+	"context"
 
 	"github.com/JubaerHossain/rootx/domain/entity"
 	"github.com/JubaerHossain/rootx/domain/infrastructure/persistence"
 	"github.com/JubaerHossain/rootx/domain/repository"
 	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/auth"
 )
 
 type App struct {
-	app  *app.App
-	repo repository.UserRepository
+	app           *app.App
+	repo          repository.UserRepository
+	refreshTokens repository.RefreshTokenRepository
+	permissions   repository.PermissionRepository
 }
 
 func AppInterface(app *app.App) *App {
 	repo := persistence.NewUserRepository(app)
 	return &App{
-		app:  app,
-		repo: repo,
+		app:           app,
+		repo:          repo,
+		refreshTokens: persistence.NewRefreshTokenRepository(app),
+		permissions:   persistence.NewPermissionRepository(app),
 	}
 }
 
-func (c *App) GetUsers(r *http.Request) (*entity.ResponsePagination, error) {
+// GetUsers returns a page of users filtered and sorted by the filter
+func (c *App) GetUsers(ctx context.Context, filter *entity.UserFilter) (*entity.ResponsePagination, error) {
 	// Call repository to get all users
-	// queryValues := r.URL.Query()
-	users, userErr := c.repo.GetAllUsers(r)
+	users, userErr := c.repo.GetAllUsers(ctx, filter)
 	if userErr != nil {
 		return nil, userErr
 	}
@@ -37,8 +40,8 @@ func (c *App) GetUsers(r *http.Request) (*entity.ResponsePagination, error) {
 }
 
 // CreateUser creates a new user
-func (c *App) CreateUser(user *entity.ValidateUser, r *http.Request) error {
-	err2 := c.repo.CreateUser(user, r)
+func (c *App) CreateUser(ctx context.Context, user *entity.ValidateUser) error {
+	err2 := c.repo.CreateUser(ctx, user)
 	if err2 != nil {
 		return err2
 	}
@@ -46,23 +49,17 @@ func (c *App) CreateUser(user *entity.ValidateUser, r *http.Request) error {
 }
 
 // GetUserByID retrieves a user by ID
-func (c *App) GetUserByID(r *http.Request) (*entity.User, error) {
-	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid user ID")
-	}
-	user, userErr := c.repo.GetUserByID(uint(id))
+func (c *App) GetUserByID(ctx context.Context, id uint) (*entity.User, error) {
+	user, userErr := c.repo.GetUserByID(ctx, id)
 	if userErr != nil {
 		return nil, userErr
 	}
 	return user, nil
 }
-func (c *App) GetUser(r *http.Request) (*entity.ResponseUser, error) {
-	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid user ID")
-	}
-	user, userErr := c.repo.GetUser(uint(id))
+
+// GetUser retrieves a user by ID
+func (c *App) GetUser(ctx context.Context, id uint) (*entity.ResponseUser, error) {
+	user, userErr := c.repo.GetUser(ctx, id)
 	if userErr != nil {
 		return nil, userErr
 	}
@@ -70,82 +67,105 @@ func (c *App) GetUser(r *http.Request) (*entity.ResponseUser, error) {
 }
 
 // GetUserDetails retrieves a user by ID
-func (c *App) GetUserDetails(r *http.Request) (*entity.ResponseUser, error) {
-	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid user ID")
-	}
-	user, userErr := c.repo.GetUserDetails(uint(id))
+func (c *App) GetUserDetails(ctx context.Context, id uint) (*entity.ResponseUser, error) {
+	user, userErr := c.repo.GetUserDetails(ctx, id)
 	if userErr != nil {
 		return nil, userErr
 	}
 	return user, nil
 }
 
-// UpdateUser updates an existing user
-func (c *App) UpdateUser(r *http.Request, user *entity.UpdateUser) (*entity.User, error) {
-	// Call repository to update user
-	oldUser, err := c.GetUserByID(r)
+// target loads the user an actor acts on, refusing a user whose role outranks the role of the actor
+func (c *App) target(ctx context.Context, actor *entity.AuthUser, id uint) (*entity.User, error) {
+	user, err := c.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-
-	updateUser, err2 := c.repo.UpdateUser(oldUser, user, r)
-	if err2 != nil {
-		return nil, err2
+	if err := auth.AuthorizeTarget(actor, user.Role); err != nil {
+		return nil, err
 	}
-	return updateUser, nil
+	return user, nil
 }
 
-// DeleteUser deletes a user by ID
-func (c *App) DeleteUser(r *http.Request) error {
-	// Call repository to delete user
-	user, err := c.GetUserByID(r)
+// UpdateUser updates an existing user, a new role or status invalidates its tokens
+func (c *App) UpdateUser(ctx context.Context, actor *entity.AuthUser, id uint, user *entity.UpdateUser) (*entity.User, error) {
+	var updateUser *entity.User
+	err := c.app.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		oldUser, err := c.target(ctx, actor, id)
+		if err != nil {
+			return err
+		}
+		updateUser, err = c.repo.UpdateUser(ctx, oldUser, user)
+		if err != nil {
+			return err
+		}
+		// The tokens carry the role and were issued to an active user
+		if updateUser.Role != oldUser.Role || updateUser.Status != oldUser.Status {
+			return c.revokeSessions(ctx, oldUser.ID)
+		}
+		return nil
+	})
 	if err != nil {
-		return err
-	}
-
-	err2 := c.repo.DeleteUser(user, r)
-	if err2 != nil {
-		return err2
+		return nil, err
 	}
+	return updateUser, nil
+}
 
-	return nil
+// DeleteUser deletes a user by ID and invalidates its tokens
+func (c *App) DeleteUser(ctx context.Context, actor *entity.AuthUser, id uint) error {
+	return c.app.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := c.target(ctx, actor, id)
+		if err != nil {
+			return err
+		}
+		if err := c.repo.DeleteUser(ctx, user); err != nil {
+			return err
+		}
+		return c.revokeSessions(ctx, user.ID)
+	})
 }
 
-// ChangePassword changes the password of a user
-func (c *App) ChangePassword(r *http.Request, user *entity.UserPasswordChange) error {
-	// Call repository to change password
-	oldUser, err := c.GetUserByID(r)
-	if err != nil {
-		return err
-	}
-	userErr := c.repo.ChangePassword(oldUser, user, r)
-	if userErr != nil {
-		return userErr
-	}
-	return nil
+// ChangePassword changes the password of a user and logs it out of every device
+func (c *App) ChangePassword(ctx context.Context, actor *entity.AuthUser, id uint, user *entity.UserPasswordChange) error {
+	return c.app.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		oldUser, err := c.target(ctx, actor, id)
+		if err != nil {
+			return err
+		}
+		if err := c.repo.ChangePassword(ctx, oldUser, user); err != nil {
+			return err
+		}
+		return c.revokeSessions(ctx, oldUser.ID)
+	})
 }
 
-// TerminateUser changes the password of a user
-func (c *App) TerminateUser(r *http.Request, user *entity.TerminateUser) error {
-	// Call repository to change password
-	oldUser, err := c.GetUserByID(r)
-	if err != nil {
-		return err
-	}
-	userErr := c.repo.TerminateUser(oldUser, user, r)
-	if userErr != nil {
-		return userErr
-	}
-	return nil
+// TerminateUser terminates a user and invalidates its tokens
+func (c *App) TerminateUser(ctx context.Context, actor *entity.AuthUser, id uint, user *entity.TerminateUser) error {
+	return c.app.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		oldUser, err := c.target(ctx, actor, id)
+		if err != nil {
+			return err
+		}
+		if err := c.repo.TerminateUser(ctx, oldUser, user); err != nil {
+			return err
+		}
+		return c.revokeSessions(ctx, oldUser.ID)
+	})
 }
 
-// Login authenticates a user
-func (c *App) Login(loginUser *entity.LoginUser) (*entity.LoginUserResponse, error) {
-	user, userErr := c.repo.Login(loginUser)
+// Login authenticates a user and starts a new family of refresh tokens for the device
+func (c *App) Login(ctx context.Context, loginUser *entity.LoginUser, device entity.Device) (*entity.LoginUserResponse, error) {
+	user, userErr := c.repo.Login(ctx, loginUser)
 	if userErr != nil {
 		return nil, userErr
 	}
+
+	familyID, err := auth.NewTokenFamily()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.issueRefreshToken(ctx, user, familyID, device); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
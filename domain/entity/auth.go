@@ -1,18 +1,26 @@
 package entity
 
-import "github.com/JubaerHossain/rootx/pkg/core/entity"
+import (
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+)
 
 type LoginUser struct {
-	Phone    string `json:"phone" validate:"required,min=11,max=15"`
-	Password string `json:"password" validate:"required,min=6,max=20"`
+	Phone    string `json:"phone" validate:"required,phone"`
+	Password string `json:"password" validate:"required,max=72"`
 }
 
+// LoginUserResponse is answered by login and refresh, Token is the access token
 type LoginUserResponse struct {
-	ID     uint          `json:"id"`
-	Name   string        `json:"name"`
-	Phone  string        `json:"phone"`
-	Status entity.Status `json:"status"`
-	Token  string        `json:"token"`
+	ID                    uint          `json:"id"`
+	Name                  string        `json:"name"`
+	Phone                 string        `json:"phone"`
+	Status                entity.Status `json:"status"`
+	Token                 string        `json:"token"`
+	TokenExpiresAt        time.Time     `json:"token_expires_at"`
+	RefreshToken          string        `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time     `json:"refresh_token_expires_at"`
 }
 type AuthUser struct {
 	ID     uint          `json:"id"`
@@ -21,3 +29,14 @@ type AuthUser struct {
 	Role   entity.Role   `json:"role"`
 	Status entity.Status `json:"status"`
 }
+
+// AuthUser returns what the access tokens of the user carry
+func (u *User) AuthUser() *AuthUser {
+	return &AuthUser{
+		ID:     u.ID,
+		Name:   u.Name,
+		Phone:  u.Phone,
+		Role:   u.Role,
+		Status: u.Status,
+	}
+}
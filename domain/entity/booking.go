@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// Booking represents a row of the booking table
+type Booking struct {
+	ID          uint      `json:"id" db:"id,default" gorm:"primaryKey;autoIncrement;not null"`
+	Name        string    `json:"name" db:"name" validate:"required,min=3,max=100" gorm:"index;not null"`
+	Description string    `json:"description" db:"description" validate:"required" gorm:"type:text;not null"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at,default" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at,default" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,25 @@
+package entity
+
+import appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
+
+// Errors of the user domain, their codes are part of the API
+var (
+	ErrUserNotFound       = appError.NewNotFound("user_not_found", "user not found")
+	ErrInvalidUserID      = appError.NewBadRequest("invalid_user_id", "invalid user ID")
+	ErrPhoneTaken         = appError.NewConflict("phone_taken", "phone is already registered")
+	ErrInvalidCredentials = appError.NewUnauthorized("invalid_credentials", "invalid phone or password")
+	ErrWrongPassword      = appError.NewValidation("wrong_password", "old password is incorrect")
+)
+
+// Errors of the refresh tokens
+var (
+	ErrInvalidRefreshToken = appError.NewUnauthorized("invalid_refresh_token", "invalid refresh token")
+	ErrRefreshTokenExpired = appError.NewUnauthorized("refresh_token_expired", "refresh token has expired")
+	ErrRefreshTokenReused  = appError.NewUnauthorized("refresh_token_reused", "refresh token was already used, log in again")
+	ErrUserNotActive       = appError.NewForbidden("user_not_active", "user is not active")
+)
+
+// Errors of the roles and permissions
+var (
+	ErrRoleOrPermissionNotFound = appError.NewNotFound("role_or_permission_not_found", "role or permission not found")
+)
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// RefreshToken is a stored refresh token. Each refresh uses it up and issues the next token of its family,
+// the tokens rotated from one login, so a token used twice reveals a stolen one.
+type RefreshToken struct {
+	ID        uint64     `json:"id" db:"id,default"`
+	UserID    uint       `json:"user_id" db:"user_id"`
+	FamilyID  string     `json:"family_id" db:"family_id"`
+	TokenHash string     `json:"-" db:"token_hash"` // SHA-256 of the token, the token itself is never stored
+	UserAgent string     `json:"user_agent" db:"user_agent"`
+	IPAddress string     `json:"ip_address" db:"ip_address"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at,default"`
+}
+
+// Device describes the client a refresh token is issued to
+type Device struct {
+	UserAgent string
+	IPAddress string
+}
+
+// RefreshTokenRequest exchanges a refresh token for new tokens
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest optionally names the refresh token of the session to end
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
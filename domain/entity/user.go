@@ -1,41 +1,46 @@
 package entity
 
 import (
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/JubaerHossain/rootx/pkg/core/entity"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
 )
 
 // User represents the user entity
 type User struct {
-	ID        uint          `json:"id" gorm:"primaryKey;autoIncrement;not null"` // Primary key
-	Name      string        `json:"name" validate:"required,min=3,max=50" gorm:"index"`
-	Phone     string        `json:"phone" validate:"required,min=11,max=15" gorm:"index;unique"`
-	Password  string        `json:"password" validate:"required,min=6,max=20" gorm:"index;not null"`
-	Role      entity.Role   `json:"role" gorm:"index;default:chef" validate:"required,oneof=admin manager waiter chef"`
-	CreatedAt time.Time     `json:"created_at" gorm:"index;autoCreateTime"`
-	UpdatedAt time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
-	Status    entity.Status `json:"status" gorm:"index;default:pending" validate:"required,oneof=active inactive deleted pending"`
+	ID        uint          `json:"id" db:"id,default" gorm:"primaryKey;autoIncrement;not null"` // Primary key
+	Name      string        `json:"name" db:"name" validate:"required,min=3,max=50" gorm:"index"`
+	Phone     string        `json:"phone" db:"phone" validate:"required,phone" gorm:"index;unique;size:15"`
+	Password  string        `json:"password" db:"password" validate:"required,strong_password" gorm:"not null;size:192"`
+	Role      entity.Role   `json:"role" db:"role" gorm:"index;default:user" validate:"required,role"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at,default" gorm:"index;autoCreateTime"`
+	UpdatedAt time.Time     `json:"updated_at" db:"updated_at,default" gorm:"autoUpdateTime"`
+	Status    entity.Status `json:"status" db:"status" gorm:"index;default:pending" validate:"required,status"`
+	// TokenVersion is incremented to invalidate every token of the user, see auth.TokenVersions
+	TokenVersion int `json:"-" db:"token_version" gorm:"not null;default:0"`
 }
 
 type ValidateUser struct {
 	ID        uint          `json:"id" gorm:"primaryKey;autoIncrement;not null"` // Primary key
 	Name      string        `json:"name" validate:"required,min=3,max=50" gorm:"index"`
-	Phone     string        `json:"phone" validate:"required,min=11,max=15" gorm:"index;unique"`
-	Password  string        `json:"password" validate:"required,min=6,max=20" gorm:"index;not null"`
-	Role      entity.Role   `json:"role" gorm:"index;default:chef" validate:"required,oneof=admin manager waiter chef"`
+	Phone     string        `json:"phone" validate:"required,phone" gorm:"index;unique"`
+	Password  string        `json:"password" validate:"required,strong_password" gorm:"not null;size:192"`
+	Role      entity.Role   `json:"role" gorm:"index;default:user" validate:"required,role"`
 	CreatedAt time.Time     `json:"created_at" gorm:"index;autoCreateTime"`
 	UpdatedAt time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
-	Status    entity.Status `json:"status" gorm:"index;default:pending" validate:"required,oneof=active inactive deleted pending"`
+	Status    entity.Status `json:"status" gorm:"index;default:pending" validate:"required,status"`
 }
 
 // updateUser represents the user update request
 type UpdateUser struct {
 	Name      string        `json:"name" validate:"omitempty,min=3,max=50"`
-	Phone     string        `json:"phone" validate:"omitempty,min=11,max=15"`
+	Phone     string        `json:"phone" validate:"omitempty,phone"`
 	UpdatedAt time.Time     `json:"updated_at" gorm:"autoUpdateTime" validate:"omitempty"`
-	Role      entity.Role   `json:"role" validate:"omitempty,oneof=admin manager waiter chef"`
-	Status    entity.Status `json:"status" gorm:"default:pending" validate:"omitempty,oneof=active inactive deleted pending"`
+	Role      entity.Role   `json:"role" validate:"omitempty,role"`
+	Status    entity.Status `json:"status" gorm:"default:pending" validate:"omitempty,status"`
 }
 
 // responseUser represents the user response
@@ -50,16 +55,83 @@ type ResponseUser struct {
 
 type TerminateUser struct {
 	ID     uint          `json:"id"`
-	Status entity.Status `json:"status"`
+	Status entity.Status `json:"status" validate:"omitempty,status"`
 }
 
 type UserPasswordChange struct {
 	ID          uint   `json:"id"`
-	OldPassword string `json:"old_password"`
-	NewPassword string `json:"new_password"`
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,strong_password"`
+}
+
+// UserFilter is a request of the user list: the page or cursor, the filters and the sort
+type UserFilter struct {
+	utilQuery.ListParams
+	// Cursor asks for keyset pagination when set, "" being the first page. Sort.Column is then empty
+	// and the users are sorted on created_at, Sort.Desc picking the direction.
+	Cursor *string
+	// CreatedFrom and CreatedTo bound created_at when not zero
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// Search is searched in the name and phone
+	Search string
+}
+
+// UserSorts are the columns the user list can be sorted on with orderBy
+var UserSorts = []string{"id", "name", "phone", "role", "status", "created_at"}
+
+// userFilterFields are the filters of the user list, e.g. status=active, role[in]=admin,manager or created_at[gte]=2024-01-01
+var userFilterFields = []utilQuery.Field{
+	{Param: "status", Ops: []utilQuery.Op{utilQuery.OpEq, utilQuery.OpIn}, Allowed: []string{string(entity.Active), string(entity.Inactive), string(entity.Pending), string(entity.Deleted)}},
+	{Param: "role", Ops: []utilQuery.Op{utilQuery.OpEq, utilQuery.OpIn}, Allowed: []string{string(entity.AdminRole), string(entity.ManagerRole), string(entity.UserRole)}},
+	{Param: "name", Ops: []utilQuery.Op{utilQuery.OpEq, utilQuery.OpLike}},
+	{Param: "phone", Ops: []utilQuery.Op{utilQuery.OpEq, utilQuery.OpLike}},
+	{Param: "created_at", Type: utilQuery.FieldTime, Ops: []utilQuery.Op{utilQuery.OpGte, utilQuery.OpLte, utilQuery.OpGt, utilQuery.OpLt}},
+}
+
+// ParseUserFilter reads the user list request from the query string. q searches the name and phone,
+// created_from and created_to are kept from before the operator syntax and cursor selects keyset pagination.
+func ParseUserFilter(queryValues url.Values) (*UserFilter, error) {
+	conditions, err := utilQuery.ParseConditions(queryValues, userFilterFields...)
+	if err != nil {
+		return nil, err
+	}
+	filter := &UserFilter{
+		ListParams: utilQuery.ListParams{Page: utilQuery.PageFromQuery(queryValues), Conditions: conditions},
+		Search:     strings.TrimSpace(queryValues.Get("q")),
+	}
+
+	if filter.CreatedFrom, err = utilQuery.DateParam(queryValues, "created_from", false); err != nil {
+		return nil, err
+	}
+	if filter.CreatedTo, err = utilQuery.DateParam(queryValues, "created_to", true); err != nil {
+		return nil, err
+	}
+
+	if !utilQuery.CursorMode(queryValues) {
+		if filter.Sort, err = utilQuery.ParseSort(queryValues, UserSorts...); err != nil {
+			return nil, err
+		}
+		return filter, nil
+	}
+
+	// Cursors page along created_at, newest first unless sortBy is asc
+	if queryValues.Get("orderBy") != "" {
+		return nil, utilQuery.InvalidParam("orderBy is not supported with cursor, use sortBy")
+	}
+	direction, err := utilQuery.OneOf(queryValues, "sortBy", "asc", "desc")
+	if err != nil {
+		return nil, err
+	}
+	cursor := queryValues.Get(utilQuery.CursorParam)
+	filter.Cursor = &cursor
+	filter.Sort.Desc = direction != "asc"
+	return filter, nil
 }
 
+// ResponsePagination is a page of users, Cursor is only set when ?cursor= asks for keyset pagination
 type ResponsePagination struct {
-	Data       []*ResponseUser   `json:"data"`
-	Pagination entity.Pagination `json:"pagination"`
+	Data       []*ResponseUser          `json:"data"`
+	Pagination entity.Pagination        `json:"pagination"`
+	Cursor     *entity.CursorPagination `json:"cursor,omitempty"`
 }
@@ -0,0 +1,19 @@
+package persistence
+
+import (
+	"github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/domain/repository"
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	coreRepository "github.com/JubaerHossain/rootx/pkg/core/database/repository"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+)
+
+// NewBookingRepository returns the repository of the booking table
+func NewBookingRepository(app *app.App) repository.BookingRepository {
+	booking := coreRepository.New[entity.Booking](app.DB, "booking")
+	booking.Filters = []utilQuery.Field{
+		{Param: "name", Ops: []utilQuery.Op{utilQuery.OpEq, utilQuery.OpLike}},
+	}
+	booking.Sorts = []string{"id", "name", "created_at"}
+	return booking
+}
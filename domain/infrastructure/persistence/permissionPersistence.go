@@ -0,0 +1,113 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/domain/repository"
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/jackc/pgx/v5"
+)
+
+// rolePermissionsTTL is how long the permissions of a role stay cached, changes made outside
+// Grant and Revoke take effect after it
+const rolePermissionsTTL = 10 * time.Minute
+
+type PermissionRepositoryImpl struct {
+	app *app.App
+}
+
+// NewPermissionRepository returns the repository of the permissions and role_permissions tables
+func NewPermissionRepository(app *app.App) repository.PermissionRepository {
+	return &PermissionRepositoryImpl{app: app}
+}
+
+// db returns the transaction of the context or the pool
+func (r *PermissionRepositoryImpl) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.app.DB)
+}
+
+// rolePermissionsKey is the cache key of the permissions of a role
+func rolePermissionsKey(role coreEntity.Role) string {
+	return fmt.Sprintf("role_permissions:%s", role)
+}
+
+// RolePermissions returns the permissions granted to a role, none for an unknown role
+func (r *PermissionRepositoryImpl) RolePermissions(ctx context.Context, role coreEntity.Role) ([]coreEntity.Permission, error) {
+	if cached, err := r.app.Cache.Get(ctx, rolePermissionsKey(role)); err == nil && cached != "" {
+		var permissions []coreEntity.Permission
+		if err := json.Unmarshal([]byte(cached), &permissions); err == nil {
+			return permissions, nil
+		}
+	}
+
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT permissions.name
+		FROM role_permissions
+		JOIN roles ON roles.id = role_permissions.role_id
+		JOIN permissions ON permissions.id = role_permissions.permission_id
+		WHERE roles.name = $1
+		ORDER BY permissions.name
+	`, string(role))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions of role %s: %w", role, appError.FromDB(err))
+	}
+	permissions, err := pgx.CollectRows(rows, pgx.RowTo[coreEntity.Permission])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions of role %s: %w", role, appError.FromDB(err))
+	}
+
+	// A cache failure only costs a query on the next request
+	if data, err := json.Marshal(permissions); err == nil {
+		r.app.Cache.Set(ctx, rolePermissionsKey(role), string(data), rolePermissionsTTL)
+	}
+	return permissions, nil
+}
+
+// Grant grants a permission to a role, ErrRoleOrPermissionNotFound when either does not exist
+func (r *PermissionRepositoryImpl) Grant(ctx context.Context, role coreEntity.Role, permission coreEntity.Permission) error {
+	var granted bool
+	err := r.db(ctx).QueryRow(ctx, `
+		WITH granted AS (
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT roles.id, permissions.id FROM roles, permissions
+			WHERE roles.name = $1 AND permissions.name = $2
+			ON CONFLICT DO NOTHING
+		)
+		SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1) AND EXISTS (SELECT 1 FROM permissions WHERE name = $2)
+	`, string(role), string(permission)).Scan(&granted)
+	if err != nil {
+		return fmt.Errorf("failed to grant %s to role %s: %w", permission, role, appError.FromDB(err))
+	}
+	if !granted {
+		return entity.ErrRoleOrPermissionNotFound
+	}
+	return r.forget(ctx, role)
+}
+
+// Revoke takes a permission back from a role
+func (r *PermissionRepositoryImpl) Revoke(ctx context.Context, role coreEntity.Role, permission coreEntity.Permission) error {
+	_, err := r.db(ctx).Exec(ctx, `
+		DELETE FROM role_permissions
+		USING roles, permissions
+		WHERE roles.id = role_permissions.role_id AND permissions.id = role_permissions.permission_id
+			AND roles.name = $1 AND permissions.name = $2
+	`, string(role), string(permission))
+	if err != nil {
+		return fmt.Errorf("failed to revoke %s from role %s: %w", permission, role, appError.FromDB(err))
+	}
+	return r.forget(ctx, role)
+}
+
+// forget removes the cached permissions of a role once the transaction commits
+func (r *PermissionRepositoryImpl) forget(ctx context.Context, role coreEntity.Role) error {
+	return database.AfterCommit(ctx, func(ctx context.Context) error {
+		return r.app.Cache.Remove(ctx, rolePermissionsKey(role))
+	})
+}
@@ -0,0 +1,88 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/domain/repository"
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	coreRepository "github.com/JubaerHossain/rootx/pkg/core/database/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type RefreshTokenRepositoryImpl struct {
+	app    *app.App
+	tokens *coreRepository.Repository[entity.RefreshToken]
+}
+
+// NewRefreshTokenRepository returns the repository of the refresh_tokens table
+func NewRefreshTokenRepository(app *app.App) repository.RefreshTokenRepository {
+	return &RefreshTokenRepositoryImpl{
+		app:    app,
+		tokens: coreRepository.New[entity.RefreshToken](app.DB, "refresh_tokens"),
+	}
+}
+
+// db returns the transaction of the context or the pool
+func (r *RefreshTokenRepositoryImpl) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.app.DB)
+}
+
+func (r *RefreshTokenRepositoryImpl) Create(ctx context.Context, token *entity.RefreshToken) error {
+	return r.tokens.Create(ctx, token)
+}
+
+// GetByHash returns the token with the hash, ErrInvalidRefreshToken when there is none. The row stays
+// locked until the transaction ends, so two refreshes with the same token cannot both rotate it.
+func (r *RefreshTokenRepositoryImpl) GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	query := "SELECT " + strings.Join(r.tokens.Columns(), ", ") + " FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE"
+	rows, err := r.db(ctx).Query(ctx, query, tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", appError.FromDB(err))
+	}
+	token, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[entity.RefreshToken])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entity.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", appError.FromDB(err))
+	}
+	return token, nil
+}
+
+// MarkUsed records that the token was exchanged, using it again revokes its family
+func (r *RefreshTokenRepositoryImpl) MarkUsed(ctx context.Context, id uint64) error {
+	if _, err := r.db(ctx).Exec(ctx, "UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to use refresh token %d: %w", id, appError.FromDB(err))
+	}
+	return nil
+}
+
+// RevokeFamily revokes the tokens of a family that are not revoked yet
+func (r *RefreshTokenRepositoryImpl) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.db(ctx).Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family %s: %w", familyID, appError.FromDB(err))
+	}
+	return nil
+}
+
+// RevokeUser revokes the refresh tokens of every device of a user
+func (r *RefreshTokenRepositoryImpl) RevokeUser(ctx context.Context, userID uint) error {
+	_, err := r.db(ctx).Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens of user %d: %w", userID, appError.FromDB(err))
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package persistence
+
+import (
+	"github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/domain/repository"
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	coreRepository "github.com/JubaerHossain/rootx/pkg/core/database/repository"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+)
+
+// NewRoleRepository returns the repository of the roles table
+func NewRoleRepository(app *app.App) repository.RoleRepository {
+	roles := coreRepository.New[entity.Role](app.DB, "roles")
+	roles.Filters = []utilQuery.Field{
+		{Param: "name", Ops: []utilQuery.Op{utilQuery.OpEq, utilQuery.OpLike}},
+	}
+	roles.Sorts = []string{"id", "name", "created_at"}
+	return roles
+}
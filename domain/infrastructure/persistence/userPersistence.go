@@ -5,49 +5,91 @@ package persistence
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/JubaerHossain/rootx/domain/entity"
 	"github.com/JubaerHossain/rootx/domain/repository"
 	"github.com/JubaerHossain/rootx/pkg/core/app"
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
 	"github.com/JubaerHossain/rootx/pkg/core/auth"
 	"github.com/JubaerHossain/rootx/pkg/core/cache"
 	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	coreRepository "github.com/JubaerHossain/rootx/pkg/core/database/repository"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
 	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+	"github.com/jackc/pgx/v5"
 )
 
 type UserRepositoryImpl struct {
-	app *app.App
+	app   *app.App
+	users *coreRepository.Repository[entity.User]
 }
 
 // NewUserRepository returns a new instance of UserRepositoryImpl
 func NewUserRepository(app *app.App) repository.UserRepository {
+	users := coreRepository.New[entity.User](app.DB, "users")
+	users.Hooks = append(users.Hooks, coreRepository.InvalidateCache(app.Cache, userCachePatterns...))
 	return &UserRepositoryImpl{
-		app: app,
+		app:   app,
+		users: users,
 	}
 }
 
-func CacheClear(req *http.Request, cache cache.CacheService) error {
-	ctx := req.Context()
-	if _, err := cache.ClearPattern(ctx, "get_all_users_*"); err != nil {
-		return err
+// db returns the transaction of the context or the pool
+func (r *UserRepositoryImpl) db(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.app.DB)
+}
+
+// userError translates a database error to the errors of the user domain,
+// phone being the only unique column of users
+func userError(err error) error {
+	err = appError.FromDB(err)
+	switch {
+	case appError.Is(err, appError.NotFound):
+		return entity.ErrUserNotFound
+	case appError.Is(err, appError.Conflict):
+		return appError.Wrap(appError.Conflict, entity.ErrPhoneTaken.Code, entity.ErrPhoneTaken.Message, err)
+	}
+	return err
+}
+
+// userCachePatterns are the cache keys cleared when users change
+var userCachePatterns = []string{"get_all_users_*", "get_payroll_users_*", "get_all_users__wise_sell_report*"}
+
+func CacheClear(ctx context.Context, cache cache.CacheService) error {
+	return coreRepository.InvalidateCache(cache, userCachePatterns...)(ctx)
+}
+
+// userColumns are the columns of a user listed by GetAllUsers
+var userColumns = []string{"id", "name", "phone", "role", "status", "created_at"}
+
+// userQuery selects the users matching the filter
+func userQuery(filter *entity.UserFilter) *utilQuery.SelectBuilder {
+	builder := utilQuery.Select("users", userColumns...).Conditions(filter.Conditions...)
+	if !filter.CreatedFrom.IsZero() {
+		builder.Range("created_at", filter.CreatedFrom, nil)
 	}
-	if _, err := cache.ClearPattern(ctx, "get_payroll_users_*"); err != nil {
-		return err
+	if !filter.CreatedTo.IsZero() {
+		builder.Range("created_at", nil, filter.CreatedTo)
 	}
-	if _, err := cache.ClearPattern(ctx, "get_all_users__wise_sell_report*"); err != nil {
-		return err
+	if filter.Search != "" {
+		builder.Like(filter.Search, "name", "phone")
 	}
-	return nil
+	return builder
 }
 
-// GetAllUsers returns all users from the database
-func (r *UserRepositoryImpl) GetAllUsers(req *http.Request) (*entity.ResponsePagination, error) {
-	// Implement logic to get all users
-	ctx := req.Context()
-	cacheKey := fmt.Sprintf("get_all_users_%s", req.URL.Query().Encode()) // Encode query parameters
+// GetAllUsers returns a page of users from the database, filtered and sorted by the filter
+func (r *UserRepositoryImpl) GetAllUsers(ctx context.Context, filter *entity.UserFilter) (*entity.ResponsePagination, error) {
+	// The filter is its own cache key, its fields are always encoded in the same order
+	key, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := fmt.Sprintf("get_all_users_%s", key)
 	if cachedData, errCache := r.app.Cache.Get(ctx, cacheKey); errCache == nil && cachedData != "" {
 		users := &entity.ResponsePagination{}
 		if err := json.Unmarshal([]byte(cachedData), users); err != nil {
@@ -56,284 +98,302 @@ func (r *UserRepositoryImpl) GetAllUsers(req *http.Request) (*entity.ResponsePag
 		return users, nil
 	}
 
+	builder := userQuery(filter)
+	var response *entity.ResponsePagination
+	if filter.Cursor != nil {
+		response, err = r.usersByCursor(ctx, filter, builder)
+	} else {
+		response, err = r.usersByPage(ctx, filter, builder)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the response
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		return &entity.ResponsePagination{}, err
+	}
+	if err := r.app.Cache.Set(ctx, cacheKey, string(jsonData), time.Duration(config.GlobalConfig.RedisExp)*time.Second); err != nil {
+		return &entity.ResponsePagination{}, err
+	}
+	return response, nil
+}
+
+// usersByPage returns a page of users by page number, newest first unless the filter sorts them
+func (r *UserRepositoryImpl) usersByPage(ctx context.Context, filter *entity.UserFilter, builder *utilQuery.SelectBuilder) (*entity.ResponsePagination, error) {
+	page := filter.Page
+	// id breaks ties so rows do not move between pages
+	builder.Sort(filter.Sort, "created_at DESC", entity.UserSorts...).ThenBy("id").Page(page)
+	query, args, err := builder.SQL()
+	if err != nil {
+		return nil, err
+	}
+	countQuery, countArgs, err := builder.CountSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	var totalItems int
+	if err := r.db(ctx).QueryRow(ctx, countQuery, countArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
 	users := []*entity.ResponseUser{}
-	query := "SELECT id, name, phone, role, status, created_at FROM users" // Example SQL query
+	if page.Offset() < totalItems {
+		rows, err := r.db(ctx).Query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query users: %w", err)
+		}
+		defer rows.Close()
+
+		// Iterate over the rows and parse the results
+		for rows.Next() {
+			var user entity.ResponseUser
+			err := rows.Scan(&user.ID, &user.Name, &user.Phone, &user.Role, &user.Status, &user.CreatedAt)
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, &user)
+		}
+
+		// Check for errors from iterating over rows
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	pagination := utilQuery.NewPagination(page, totalItems)
+	return &entity.ResponsePagination{Data: users, Pagination: pagination}, nil
+}
 
-	// Get database connection from pool
-	conn, err := r.app.DB.Acquire(ctx)
+// usersByCursor returns a page of users after or before the cursor of the filter, along created_at
+// in the direction of its sort. It skips the COUNT so deep pages cost as much as the first one.
+func (r *UserRepositoryImpl) usersByCursor(ctx context.Context, filter *entity.UserFilter, builder *utilQuery.SelectBuilder) (*entity.ResponsePagination, error) {
+	keyset, err := utilQuery.NewKeyset(*filter.Cursor, filter.Page.Size, filter.Sort.Desc,
+		utilQuery.KeyColumn{Name: "created_at", Type: "timestamp"},
+		utilQuery.KeyColumn{Name: "id", Type: "integer"},
+	)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Release()
 
-	// Perform the query
-	rows, err := conn.Query(ctx, query)
+	query, args, err := builder.Keyset(keyset).SQL()
 	if err != nil {
 		return nil, err
 	}
+	rows, err := r.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
 	defer rows.Close()
 
-	// Iterate over the rows and parse the results
+	users := []*entity.ResponseUser{}
 	for rows.Next() {
 		var user entity.ResponseUser
-		err := rows.Scan(&user.ID, &user.Name, &user.Phone, &user.Role, &user.Status, &user.CreatedAt)
-		if err != nil {
+		if err := rows.Scan(&user.ID, &user.Name, &user.Phone, &user.Role, &user.Status, &user.CreatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, &user)
 	}
-
-	// Check for errors from iterating over rows
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	response := entity.ResponsePagination{
-		Data: users,
-		// Other pagination details can be set here
-	}
-
-	// Cache the response
-	jsonData, err := json.Marshal(response)
-	if err != nil {
-		return &entity.ResponsePagination{}, err
-	}
-	if err := r.app.Cache.Set(ctx, cacheKey, string(jsonData), time.Duration(config.GlobalConfig.RedisExp)*time.Second); err != nil {
-		return &entity.ResponsePagination{}, err
-	}
-	return &response, nil
+	users, cursor := utilQuery.KeysetPage(keyset, users, func(user *entity.ResponseUser) []interface{} {
+		return []interface{}{user.CreatedAt, user.ID}
+	})
+	return &entity.ResponsePagination{Data: users, Cursor: &cursor}, nil
 }
 
-
 // GetUserByID returns a user by ID from the database
-func (r *UserRepositoryImpl) GetUserByID(userID uint) (*entity.User, error) {
-	// Implement logic to get user by ID
-	user := &entity.User{}
-	if err := r.app.DB.QueryRow(context.Background(), "SELECT id, name, phone FROM users WHERE id = $1", userID).Scan(&user.ID, &user.Name, &user.Phone); err != nil {
-		return nil, fmt.Errorf("user not found")
+func (r *UserRepositoryImpl) GetUserByID(ctx context.Context, userID uint) (*entity.User, error) {
+	user, err := r.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, userError(err)
 	}
 	return user, nil
 }
 
 // GetUser returns a user by ID from the database
-func (r *UserRepositoryImpl) GetUser(userID uint) (*entity.ResponseUser, error) {
+func (r *UserRepositoryImpl) GetUser(ctx context.Context, userID uint) (*entity.ResponseUser, error) {
 	// Implement logic to get user by ID
 	resUser := &entity.ResponseUser{}
 	query := "SELECT id, name, phone FROM users WHERE id = $1"
-	if err := r.app.DB.QueryRow(context.Background(), query, userID).Scan(&resUser.ID, &resUser.Name, &resUser.Phone); err != nil {
-		return nil, fmt.Errorf("user not found")
+	if err := r.db(ctx).QueryRow(ctx, query, userID).Scan(&resUser.ID, &resUser.Name, &resUser.Phone); err != nil {
+		return nil, userError(err)
 	}
 	return resUser, nil
 }
 
-func (r *UserRepositoryImpl) GetUserDetails(userID uint) (*entity.ResponseUser, error) {
+func (r *UserRepositoryImpl) GetUserDetails(ctx context.Context, userID uint) (*entity.ResponseUser, error) {
 	// Implement logic to get user details by ID
 	resUser := &entity.ResponseUser{}
-	err := r.app.DB.QueryRow(context.Background(), `
+	err := r.db(ctx).QueryRow(ctx, `
 		SELECT u.id, u.name, u.phone, u.role, u.status
 		FROM users u
 		WHERE u.id = $1
 	`, userID).Scan(&resUser.ID, &resUser.Name, &resUser.Phone, &resUser.Role, &resUser.Status)
 	if err != nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, userError(err)
 	}
 	return resUser, nil
 }
 
-func (r *UserRepositoryImpl) CreateUser(user *entity.ValidateUser, req *http.Request) error {
-	// Begin a transaction
-	tx, err := r.app.DB.Begin(context.Background())
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			// Recover from panic and rollback the transaction
-			tx.Rollback(context.Background())
-		} else if err := tx.Commit(context.Background()); err != nil {
-			// Commit the transaction if no error occurred, otherwise rollback
-			tx.Rollback(context.Background())
-		}
-	}()
-
+func (r *UserRepositoryImpl) CreateUser(ctx context.Context, user *entity.ValidateUser) error {
 	// Hash the password securely
 	hashedPassword, err := utilQuery.HashPassword(user.Password)
 	if err != nil {
-		tx.Rollback(context.Background())
 		return err
 	}
 	user.Password = hashedPassword
 
-	// Create the user within the transaction
-	_, err = tx.Exec(context.Background(), `
-		INSERT INTO users (name, phone, password) VALUES ($1, $2, $3)
-	`, user.Name, user.Phone, user.Password)
+	err = r.users.Create(ctx, &entity.User{
+		Name:     user.Name,
+		Phone:    user.Phone,
+		Password: user.Password,
+		Role:     user.Role,
+		Status:   user.Status,
+	})
 	if err != nil {
-		tx.Rollback(context.Background())
-		return err
-	}
-
-	// Clear cache
-	if err := CacheClear(req, r.app.Cache); err != nil {
-		tx.Rollback(context.Background())
-		return err
+		return userError(err)
 	}
-
 	return nil
 }
 
-func (r *UserRepositoryImpl) UpdateUser(oldUser *entity.User, user *entity.UpdateUser, req *http.Request) (*entity.User, error) {
-	tx, err := r.app.DB.Begin(context.Background())
-	if err != nil {
-		return nil, err
+func (r *UserRepositoryImpl) UpdateUser(ctx context.Context, oldUser *entity.User, user *entity.UpdateUser) (*entity.User, error) {
+	// Only the fields given are updated
+	fields := map[string]any{}
+	if user.Name != "" {
+		fields["name"] = user.Name
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback(context.Background())
-		} else if err := tx.Commit(context.Background()); err != nil {
-			tx.Rollback(context.Background())
-		}
-	}()
-
-	query := `
-		UPDATE users
-		SET name = $1, phone = $2, role = $3, status = $4
-		WHERE id = $5
-		RETURNING id, name, phone, role, status
-	`
-	row := tx.QueryRow(context.Background(), query, user.Name, user.Phone, user.Role, user.Status, oldUser.ID)
-	updateUser := &entity.User{}
-	err = row.Scan(&updateUser.ID, &updateUser.Name, &updateUser.Phone, &updateUser.Role, &updateUser.Status)
-	if err != nil {
-		tx.Rollback(context.Background())
-		return nil, err
+	if user.Phone != "" {
+		fields["phone"] = user.Phone
 	}
-
-	// Clear cache
-	if err := CacheClear(req, r.app.Cache); err != nil {
-		tx.Rollback(context.Background())
-		return nil, err
+	if user.Role != "" {
+		fields["role"] = user.Role
 	}
-
-	return updateUser, nil
-}
-
-func (r *UserRepositoryImpl) DeleteUser(user *entity.User, req *http.Request) error {
-	tx, err := r.app.DB.Begin(context.Background())
+	if user.Status != "" {
+		fields["status"] = user.Status
+	}
+	updateUser, err := r.users.Update(ctx, oldUser.ID, fields)
 	if err != nil {
-		return err
+		return nil, userError(err)
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback(context.Background())
-		} else if err := tx.Commit(context.Background()); err != nil {
-			tx.Rollback(context.Background())
-		}
-	}()
+	return updateUser, nil
+}
 
-	query := "DELETE FROM users WHERE id = $1"
-	if _, err := tx.Exec(context.Background(), query, user.ID); err != nil {
-		tx.Rollback(context.Background())
-		return err
+func (r *UserRepositoryImpl) DeleteUser(ctx context.Context, user *entity.User) error {
+	if err := r.users.Delete(ctx, user.ID); err != nil {
+		return userError(err)
 	}
-
-	// Clear cache
-	if err := CacheClear(req, r.app.Cache); err != nil {
-		tx.Rollback(context.Background())
-		return err
-	}
-
 	return nil
 }
 
-func (r *UserRepositoryImpl) ChangePassword(oldUser *entity.User, user *entity.UserPasswordChange, req *http.Request) error {
-	tx, err := r.app.DB.Begin(context.Background())
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback(context.Background())
-		} else if err := tx.Commit(context.Background()); err != nil {
-			tx.Rollback(context.Background())
-		}
-	}()
-
+func (r *UserRepositoryImpl) ChangePassword(ctx context.Context, oldUser *entity.User, user *entity.UserPasswordChange) error {
 	if err := utilQuery.ComparePassword(oldUser.Password, user.OldPassword); err != nil {
-		tx.Rollback(context.Background())
-		return err
+		return entity.ErrWrongPassword
 	}
 
 	newPassword, err := utilQuery.HashPassword(user.NewPassword)
 	if err != nil {
-		tx.Rollback(context.Background())
 		return err
 	}
 
-	query := "UPDATE users SET password = $1 WHERE id = $2"
-	if _, err := tx.Exec(context.Background(), query, newPassword, oldUser.ID); err != nil {
-		tx.Rollback(context.Background())
-		return err
+	if _, err = r.users.Update(ctx, oldUser.ID, map[string]any{"password": newPassword}); err != nil {
+		return userError(err)
 	}
-
 	return nil
 }
 
-func (r *UserRepositoryImpl) TerminateUser(oldUser *entity.User, user *entity.TerminateUser, req *http.Request) error {
-	tx, err := r.app.DB.Begin(context.Background())
-	if err != nil {
-		return err
+func (r *UserRepositoryImpl) TerminateUser(ctx context.Context, oldUser *entity.User, user *entity.TerminateUser) error {
+	// Implement logic to terminate user
+	if err := r.users.Delete(ctx, oldUser.ID); err != nil {
+		return userError(err)
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback(context.Background())
-		} else if err := tx.Commit(context.Background()); err != nil {
-			tx.Rollback(context.Background())
+	return nil
+}
+
+// tokenVersionKey is the cache key of the token version of a user
+func tokenVersionKey(userID uint) string {
+	return fmt.Sprintf("token_version:%d", userID)
+}
+
+// tokenVersionTTL bounds how long a cached token version can lag behind a revocation, see RevokeTokens
+const tokenVersionTTL = time.Minute
+
+// TokenVersion returns the token version of a user, cached for tokenVersionTTL
+func (r *UserRepositoryImpl) TokenVersion(ctx context.Context, userID uint) (int, error) {
+	if cached, err := r.app.Cache.Get(ctx, tokenVersionKey(userID)); err == nil && cached != "" {
+		if version, err := strconv.Atoi(cached); err == nil {
+			return version, nil
 		}
-	}()
+	}
 
-	// Implement logic to terminate user
-	query := "DELETE FROM users WHERE id = $1"
-	if _, err := tx.Exec(context.Background(), query, oldUser.ID); err != nil {
-		tx.Rollback(context.Background())
-		return err
+	var version int
+	err := r.db(ctx).QueryRow(ctx, "SELECT token_version FROM users WHERE id = $1", userID).Scan(&version)
+	if err != nil {
+		return 0, userError(err)
 	}
+	// A cache failure only costs a query on the next request
+	r.app.Cache.Set(ctx, tokenVersionKey(userID), strconv.Itoa(version), tokenVersionTTL)
+	return version, nil
+}
 
-	// Clear cache
-	if err := CacheClear(req, r.app.Cache); err != nil {
-		tx.Rollback(context.Background())
+// RevokeTokens increments the token version of a user, which invalidates every access token issued so far.
+// The cached version is removed before the commit, so a cache failure aborts the revocation instead of
+// leaving the old version accepted. It is removed again after the commit in case a request cached the
+// old version meanwhile; should that fail, the old version expires within tokenVersionTTL.
+func (r *UserRepositoryImpl) RevokeTokens(ctx context.Context, userID uint) error {
+	_, err := r.db(ctx).Exec(ctx, "UPDATE users SET token_version = token_version + 1 WHERE id = $1", userID)
+	if err != nil {
+		return userError(err)
+	}
+	// A deleted user has no row to update and no tokens left to accept, its cached version goes too
+	if err := r.app.Cache.Remove(ctx, tokenVersionKey(userID)); err != nil {
 		return err
 	}
-
-	return nil
+	return database.AfterCommit(ctx, func(ctx context.Context) error {
+		r.app.Cache.Remove(ctx, tokenVersionKey(userID))
+		return nil
+	})
 }
 
-func (r *UserRepositoryImpl) Login(loginUser *entity.LoginUser) (*entity.LoginUserResponse, error) {
+func (r *UserRepositoryImpl) Login(ctx context.Context, loginUser *entity.LoginUser) (*entity.LoginUserResponse, error) {
 	user := &entity.User{}
-	err := r.app.DB.QueryRow(context.Background(), `
-		SELECT id, name, phone, status, password
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT id, name, phone, role, status, password, token_version
 		FROM users
 		WHERE phone = $1
-	`, loginUser.Phone).Scan(&user.ID, &user.Name, &user.Phone, &user.Status, &user.Password)
+	`, loginUser.Phone).Scan(&user.ID, &user.Name, &user.Phone, &user.Role, &user.Status, &user.Password, &user.TokenVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Same answer as a wrong password, so phones cannot be probed
+		return nil, entity.ErrInvalidCredentials
+	}
 	if err != nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, appError.FromDB(err)
 	}
 
 	if err := utilQuery.ComparePassword(user.Password, loginUser.Password); err != nil {
-		return nil, fmt.Errorf("invalid password")
+		return nil, entity.ErrInvalidCredentials
+	}
+	// Terminated and inactive users are refused as on refresh, only after the password so statuses cannot be probed
+	if user.Status != coreEntity.Active {
+		return nil, entity.ErrUserNotActive
 	}
 
-	token, err := auth.CreateToken(user)
+	token, expiresAt, err := auth.CreateToken(user.AuthUser(), user.TokenVersion)
 	if err != nil {
 		return nil, err
 	}
 
 	responseTokenUser := &entity.LoginUserResponse{
-		ID:       user.ID,
-		Name: user.Name,
-		Phone:    user.Phone,
-		Status:   user.Status,
-		Token:    token,
+		ID:             user.ID,
+		Name:           user.Name,
+		Phone:          user.Phone,
+		Status:         user.Status,
+		Token:          token,
+		TokenExpiresAt: expiresAt,
 	}
 	return responseTokenUser, nil
 }
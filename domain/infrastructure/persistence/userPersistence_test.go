@@ -0,0 +1,110 @@
+package persistence
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/auth"
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+	"github.com/jackc/pgx/v5"
+)
+
+// rowTx is a transaction answering every QueryRow with row, nil for no rows
+type rowTx struct {
+	pgx.Tx
+	row []any
+}
+
+func (tx *rowTx) QueryRow(context.Context, string, ...any) pgx.Row { return tx }
+func (tx *rowTx) Commit(context.Context) error                     { return nil }
+func (tx *rowTx) Rollback(context.Context) error                   { return nil }
+
+func (tx *rowTx) Scan(dest ...any) error {
+	if tx.row == nil {
+		return pgx.ErrNoRows
+	}
+	for i, value := range tx.row {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+// rowDB begins rowTx transactions
+type rowDB struct {
+	tx *rowTx
+}
+
+func (db rowDB) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) { return db.tx, nil }
+
+func TestLogin(t *testing.T) {
+	saved := config.GlobalConfig
+	config.GlobalConfig = &config.Config{}
+	t.Cleanup(func() { config.GlobalConfig = saved })
+	key, err := auth.NewKey("test", ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.NewKeySet([]*auth.Key{key}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.SetSigner(keys)
+	t.Cleanup(func() { auth.SetSigner(nil) })
+
+	hash, err := utilQuery.HashPassword("Password#123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := func(status coreEntity.Status) []any {
+		return []any{uint(7), "Jubaer", "01700000000", coreEntity.UserRole, status, hash, 0}
+	}
+
+	tests := []struct {
+		name     string
+		row      []any
+		password string
+		wantErr  error
+	}{
+		{name: "active user", row: user(coreEntity.Active), password: "Password#123"},
+		{name: "unknown phone", password: "Password#123", wantErr: entity.ErrInvalidCredentials},
+		{name: "wrong password", row: user(coreEntity.Active), password: "Password#124", wantErr: entity.ErrInvalidCredentials},
+		{name: "inactive user", row: user(coreEntity.Inactive), password: "Password#123", wantErr: entity.ErrUserNotActive},
+		{name: "pending user", row: user(coreEntity.Pending), password: "Password#123", wantErr: entity.ErrUserNotActive},
+		{name: "terminated user", row: user(coreEntity.Deleted), password: "Password#123", wantErr: entity.ErrUserNotActive},
+		{
+			name:     "wrong password of a terminated user",
+			row:      user(coreEntity.Deleted),
+			password: "Password#124",
+			wantErr:  entity.ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &rowTx{row: tt.row}
+			repo := &UserRepositoryImpl{app: &app.App{Tx: database.NewTxManager(rowDB{tx: tx})}}
+
+			var response *entity.LoginUserResponse
+			err := repo.app.Tx.WithinTx(context.Background(), func(ctx context.Context) error {
+				var err error
+				response, err = repo.Login(ctx, &entity.LoginUser{Phone: "01700000000", Password: tt.password})
+				return err
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (response == nil || response.Token == "") {
+				t.Errorf("Login() = %+v, want a token", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package apiHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/middleware"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+	"github.com/JubaerHossain/rootx/pkg/utils"
+)
+
+// device describes the client of the request, recorded with its refresh tokens
+func device(r *http.Request) entity.Device {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return entity.Device{UserAgent: r.UserAgent(), IPAddress: ip}
+}
+
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. A refresh token
+// @Description can be used once, using it again revokes every token issued since the login. The tokens
+// @Description of a user that is no longer active are revoked too.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body entity.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} entity.LoginUserResponse
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /auth/refresh [post]
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var request entity.RefreshTokenRequest
+	if err := utilQuery.BodyParse(&request, w, r, true); err != nil {
+		return
+	}
+
+	tokens, err := h.App.Refresh(r.Context(), request.RefreshToken, device(r))
+	if err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.ReturnResponse(w, http.StatusOK, "Tokens refreshed", tokens)
+}
+
+// @Summary Log out
+// @Description Revoke the access token of the request. Send the refresh token as well to end the session,
+// @Description otherwise it can still be refreshed.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body entity.LogoutRequest false "Refresh token of the session"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /auth/logout [post]
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.TokenFrom(r.Context())
+	if !ok {
+		utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: missing token")
+		return
+	}
+
+	// The body is optional
+	var request entity.LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		utils.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.App.Logout(r.Context(), token, request.RefreshToken); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.ReturnResponse(w, http.StatusOK, "Logged out", nil)
+}
+
+// @Summary Log out of every device
+// @Description Invalidate every access token and refresh token of the user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /auth/logout-all [post]
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.TokenFrom(r.Context())
+	if !ok {
+		utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: missing token")
+		return
+	}
+
+	if err := h.App.LogoutAll(r.Context(), token.User.ID); err != nil {
+		utils.WriteError(w, err)
+		return
+	}
+
+	utils.ReturnResponse(w, http.StatusOK, "Logged out of every device", nil)
+}
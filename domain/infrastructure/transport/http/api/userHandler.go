@@ -1,12 +1,14 @@
 package apiHandler
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/JubaerHossain/rootx/domain/application"
 	"github.com/JubaerHossain/rootx/domain/entity"
 	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/auth"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
 	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
 	"github.com/JubaerHossain/rootx/pkg/utils"
 )
@@ -23,26 +25,72 @@ func NewHandler(app *app.App) *Handler {
 	}
 }
 
+// userID reads the id path parameter, answering invalid_user_id when it is not a valid ID
+func userID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil || id == 0 {
+		utils.WriteError(w, entity.ErrInvalidUserID)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// actor reads the authenticated user, answering forbidden when there is none
+func actor(w http.ResponseWriter, r *http.Request) (*entity.AuthUser, bool) {
+	user, err := auth.User(r)
+	if err != nil {
+		utils.WriteError(w, auth.ErrForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+// authorize returns auth.ErrForbidden unless the role of the authenticated user grants the permission
+func (h *Handler) authorize(r *http.Request, permission coreEntity.Permission) error {
+	user, err := auth.User(r)
+	if err != nil {
+		return auth.ErrForbidden
+	}
+	return auth.Authorize(r.Context(), h.App, user, permission)
+}
+
 // @Summary Get all users
-// @Description Get details of all users
+// @Description Get a page of users, filtered and sorted
 // @Tags users
 // @Accept json
 // @Produce json
-// @Success 200 {array} entity.ResponseUser
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Users per page, at most 100" default(10)
+// @Param status query string false "Status" Enums(active, inactive, pending, deleted)
+// @Param role query string false "Role" Enums(admin, manager, user)
+// @Param created_from query string false "Created on or after, 2006-01-02 or RFC 3339"
+// @Param created_to query string false "Created on or before, 2006-01-02 or RFC 3339"
+// @Param q query string false "Text searched in the name and phone"
+// @Param orderBy query string false "Sort column" Enums(id, name, phone, role, status, created_at)
+// @Param sortBy query string false "Sort direction" Enums(asc, desc)
+// @Param cursor query string false "Keyset pagination, empty for the first page then next_cursor or prev_cursor"
+// @Success 200 {object} entity.ResponsePagination
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
 // @Router /users [get]
 func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	// Implement GetUsers handler
-	users, err := h.App.GetUsers(r)
+	// The query string is parsed here, the layers below only see the typed filter
+	filter, err := entity.ParseUserFilter(r.URL.Query())
 	if err != nil {
-		fmt.Println("err")
-		fmt.Println(err)
-		// logger.Error("Failed to fetch users", zap.Error(err))
-		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to fetch users")
+		utils.WriteError(w, err)
+		return
+	}
+	users, err := h.App.GetUsers(r.Context(), filter)
+	if err != nil {
+		utils.WriteError(w, err)
 		return
 	}
 	// user, err := auth.User(r)
 	// if err != nil {
-	// 	utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+	// 	utils.WriteError(w, err)
 	// 	return
 	// }
 	// fmt.Println("user")
@@ -54,6 +102,20 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// @Summary Create a user
+// @Description Create a user with a hashed password
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body entity.ValidateUser true "User to create"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /users [post]
 func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	// Implement CreateUser handler
 	var newUser entity.ValidateUser
@@ -62,11 +124,18 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	if pareErr != nil {
 		return
 	}
+	// Creating users of another role than user is giving them that role
+	if newUser.Role != coreEntity.UserRole {
+		if err := h.authorize(r, coreEntity.RolesAssign); err != nil {
+			utils.WriteError(w, err)
+			return
+		}
+	}
 
 	// Call the CreateUser function to create the user
-	err := h.App.CreateUser(&newUser, r)
+	err := h.App.CreateUser(r.Context(), &newUser)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, err)
 		return
 	}
 
@@ -76,10 +145,27 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// @Summary Get a user
+// @Description Get the name and phone of a user
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} entity.ResponseUser
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /users/{id} [get]
 func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
-	user, err := h.App.GetUser(r)
+	id, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.App.GetUser(r.Context(), id)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, err)
 		return
 	}
 	// Write response
@@ -90,11 +176,27 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// GetUserDetails  get user details
+// @Summary Get user details
+// @Description Get a user with its role and status
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} entity.ResponseUser
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /users/{id}/details [get]
 func (h *Handler) GetUserDetails(w http.ResponseWriter, r *http.Request) {
-	user, err := h.App.GetUserDetails(r)
+	id, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.App.GetUserDetails(r.Context(), id)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, err)
 		return
 	}
 	// Write response
@@ -105,18 +207,56 @@ func (h *Handler) GetUserDetails(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// @Summary Update a user
+// @Description Update the name, phone, role and status of a user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param user body entity.UpdateUser true "Fields to update"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /users/{id} [patch]
 func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	// Implement UpdateUser handler
+	id, ok := userID(w, r)
+	if !ok {
+		return
+	}
 	var updateUser entity.UpdateUser
 	pareErr := utilQuery.BodyParse(&updateUser, w, r, true) // Parse request body and validate it
 	if pareErr != nil {
 		return
 	}
+	// Users may edit their own profile, but not their role nor their status
+	if updateUser.Role != "" {
+		if err := h.authorize(r, coreEntity.RolesAssign); err != nil {
+			utils.WriteError(w, err)
+			return
+		}
+	}
+	if updateUser.Status != "" {
+		if err := h.authorize(r, coreEntity.UsersUpdate); err != nil {
+			utils.WriteError(w, err)
+			return
+		}
+	}
+
+	user, ok := actor(w, r)
+	if !ok {
+		return
+	}
 
 	// Call the CreateUser function to create the user
-	_, err := h.App.UpdateUser(r, &updateUser)
+	_, err := h.App.UpdateUser(r.Context(), user, id, &updateUser)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, err)
 		return
 	}
 
@@ -126,11 +266,31 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// @Summary Delete a user
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /users/{id} [delete]
 func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	// Implement DeleteUser handler
-	err := h.App.DeleteUser(r)
+	id, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	user, ok := actor(w, r)
+	if !ok {
+		return
+	}
+	err := h.App.DeleteUser(r.Context(), user, id)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, err)
 		return
 	}
 	// Write response
@@ -139,18 +299,42 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// @Summary Change the password of a user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param password body entity.UserPasswordChange true "Old and new password"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 422 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /users/{id}/password [patch]
 func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	// Implement ChangePassword handler
+	id, ok := userID(w, r)
+	if !ok {
+		return
+	}
 	var updateUser entity.UserPasswordChange
 	pareErr := utilQuery.BodyParse(&updateUser, w, r, true) // Parse request body and validate it
 	if pareErr != nil {
 		return
 	}
 
+	user, ok := actor(w, r)
+	if !ok {
+		return
+	}
+
 	// Call the CreateUser function to create the user
-	err := h.App.ChangePassword(r, &updateUser)
+	err := h.App.ChangePassword(r.Context(), user, id, &updateUser)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, err)
 		return
 	}
 
@@ -159,17 +343,40 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		"message": "Password changed successfully",
 	})
 }
+
+// @Summary Terminate a user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param user body entity.TerminateUser true "User to terminate"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Security BearerAuth
+// @Router /users/{id}/terminate [post]
 func (h *Handler) TerminateUser(w http.ResponseWriter, r *http.Request) {
 	// Implement TerminateUser handler
+	id, ok := userID(w, r)
+	if !ok {
+		return
+	}
 	var updateUser entity.TerminateUser
 	pareErr := utilQuery.BodyParse(&updateUser, w, r, true) // Parse request body and validate it
 	if pareErr != nil {
 		return
 	}
+	user, ok := actor(w, r)
+	if !ok {
+		return
+	}
 	// Call the CreateUser function to create the user
-	err := h.App.TerminateUser(r, &updateUser)
+	err := h.App.TerminateUser(r.Context(), user, id, &updateUser)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, err)
 		return
 	}
 
@@ -179,6 +386,17 @@ func (h *Handler) TerminateUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// @Summary Log in
+// @Description Exchange a phone and password for an access token and a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body entity.LoginUser true "Phone and password"
+// @Success 200 {object} entity.LoginUserResponse
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /auth/login [post]
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	// Implement Login handler
 	var loginUser entity.LoginUser
@@ -188,9 +406,9 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call the CreateUser function to create the user
-	user, err := h.App.Login(&loginUser)
+	user, err := h.App.Login(r.Context(), &loginUser, device(r))
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, err)
 		return
 	}
 
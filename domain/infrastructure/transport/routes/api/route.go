@@ -5,6 +5,8 @@ import (
 
 	apiHandler "github.com/JubaerHossain/rootx/domain/infrastructure/transport/http/api"
 	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/middleware"
 )
 
 // APIRouter registers routes for API endpoints
@@ -14,8 +16,26 @@ func APIRouter(application *app.App) http.Handler {
 	// Register user routes
 	apiHandler := apiHandler.NewHandler(application)
 
-	// Register user routes
-	router.Handle("/users", http.HandlerFunc(apiHandler.GetUsers))
+	// Tokens are checked against the revocation list and the token version of their user
+	authenticator := middleware.NewAuthenticator(application.Cache, apiHandler.App)
+	// Routes are guarded by the permissions granted to the role of the user
+	authorizer := middleware.NewAuthorizer(apiHandler.App)
+
+	// Register auth routes
+	router.Handle("POST /auth/login", http.HandlerFunc(apiHandler.Login))
+	router.Handle("POST /auth/refresh", http.HandlerFunc(apiHandler.Refresh))
+	router.Handle("POST /auth/logout", authenticator.Authenticate(http.HandlerFunc(apiHandler.Logout)))
+	router.Handle("POST /auth/logout-all", authenticator.Authenticate(http.HandlerFunc(apiHandler.LogoutAll)))
+
+	// Register user routes, they require a token and a permission of its role, or being the user itself
+	router.Handle("GET /users", authenticator.Authenticate(authorizer.RequirePermission(entity.UsersList)(http.HandlerFunc(apiHandler.GetUsers))))
+	router.Handle("POST /users", authenticator.Authenticate(authorizer.RequirePermission(entity.UsersCreate)(http.HandlerFunc(apiHandler.CreateUser))))
+	router.Handle("GET /users/{id}", authenticator.Authenticate(authorizer.RequireSelfOrPermission("id", entity.UsersRead)(http.HandlerFunc(apiHandler.GetUser))))
+	router.Handle("GET /users/{id}/details", authenticator.Authenticate(authorizer.RequireSelfOrPermission("id", entity.UsersRead)(http.HandlerFunc(apiHandler.GetUserDetails))))
+	router.Handle("PATCH /users/{id}", authenticator.Authenticate(authorizer.RequireSelfOrPermission("id", entity.UsersUpdate)(http.HandlerFunc(apiHandler.UpdateUser))))
+	router.Handle("DELETE /users/{id}", authenticator.Authenticate(authorizer.RequirePermission(entity.UsersDelete)(http.HandlerFunc(apiHandler.DeleteUser))))
+	router.Handle("PATCH /users/{id}/password", authenticator.Authenticate(authorizer.RequireSelfOrPermission("id", entity.UsersUpdate)(http.HandlerFunc(apiHandler.ChangePassword))))
+	router.Handle("POST /users/{id}/terminate", authenticator.Authenticate(authorizer.RequirePermission(entity.UsersTerminate)(http.HandlerFunc(apiHandler.TerminateUser))))
 
-	return router
+	return middleware.MethodNotAllowed(router)
 }
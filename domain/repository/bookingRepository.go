@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"github.com/JubaerHossain/rootx/domain/entity"
+	coreRepository "github.com/JubaerHossain/rootx/pkg/core/database/repository"
+)
+
+// BookingRepository defines methods for booking data access
+type BookingRepository interface {
+	coreRepository.CRUD[entity.Booking]
+}
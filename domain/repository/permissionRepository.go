@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+)
+
+// PermissionRepository defines methods for the permissions granted to roles
+type PermissionRepository interface {
+	RolePermissions(ctx context.Context, role coreEntity.Role) ([]coreEntity.Permission, error)
+	Grant(ctx context.Context, role coreEntity.Role, permission coreEntity.Permission) error
+	Revoke(ctx context.Context, role coreEntity.Role, permission coreEntity.Permission) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/JubaerHossain/rootx/domain/entity"
+)
+
+// RefreshTokenRepository defines methods for refresh token data access
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *entity.RefreshToken) error
+	// GetByHash returns the token with the hash and locks it until the end of the transaction
+	GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	MarkUsed(ctx context.Context, id uint64) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"github.com/JubaerHossain/rootx/domain/entity"
+	coreRepository "github.com/JubaerHossain/rootx/pkg/core/database/repository"
+)
+
+// RoleRepository defines methods for role data access
+type RoleRepository interface {
+	coreRepository.CRUD[entity.Role]
+}
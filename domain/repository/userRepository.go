@@ -1,21 +1,23 @@
 package repository
 
 import (
-	"net/http"
+	"context"
 
 	"github.com/JubaerHossain/rootx/domain/entity"
 )
 
 // UserRepository defines methods for user data access
 type UserRepository interface {
-	GetAllUsers(r *http.Request) (*entity.ResponsePagination, error)
-	GetUserByID(userID uint) (*entity.User, error)
-	GetUser(userID uint) (*entity.ResponseUser, error)
-	GetUserDetails(userID uint) (*entity.ResponseUser, error)
-	CreateUser(user *entity.ValidateUser, r *http.Request) error
-	UpdateUser(oldUser *entity.User, user *entity.UpdateUser, req *http.Request) (*entity.User, error)
-	DeleteUser(user *entity.User, req *http.Request) error
-	ChangePassword(oldUser *entity.User, user *entity.UserPasswordChange, r *http.Request) error
-	TerminateUser(oldUser *entity.User, user *entity.TerminateUser, r *http.Request) error
-	Login(loginUser *entity.LoginUser) (*entity.LoginUserResponse, error)
+	GetAllUsers(ctx context.Context, filter *entity.UserFilter) (*entity.ResponsePagination, error)
+	GetUserByID(ctx context.Context, userID uint) (*entity.User, error)
+	GetUser(ctx context.Context, userID uint) (*entity.ResponseUser, error)
+	GetUserDetails(ctx context.Context, userID uint) (*entity.ResponseUser, error)
+	CreateUser(ctx context.Context, user *entity.ValidateUser) error
+	UpdateUser(ctx context.Context, oldUser *entity.User, user *entity.UpdateUser) (*entity.User, error)
+	DeleteUser(ctx context.Context, user *entity.User) error
+	ChangePassword(ctx context.Context, oldUser *entity.User, user *entity.UserPasswordChange) error
+	TerminateUser(ctx context.Context, oldUser *entity.User, user *entity.TerminateUser) error
+	Login(ctx context.Context, loginUser *entity.LoginUser) (*entity.LoginUserResponse, error)
+	TokenVersion(ctx context.Context, userID uint) (int, error)
+	RevokeTokens(ctx context.Context, userID uint) error
 }
@@ -0,0 +1,14 @@
+// Package rootx bundles the SQL migrations and seeds into the binaries
+package rootx
+
+import "embed"
+
+// Migrations holds the migrations directory
+//
+//go:embed migrations
+var Migrations embed.FS
+
+// Seeds holds the seeds directory
+//
+//go:embed seeds
+var Seeds embed.FS
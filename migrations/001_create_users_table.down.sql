@@ -0,0 +1,3 @@
+-- Rollback users
+
+DROP TABLE IF EXISTS users;
@@ -1,4 +1,3 @@
-DROP TABLE IF EXISTS users;
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     name VARCHAR(50) NOT NULL,
@@ -10,12 +9,9 @@ CREATE TABLE IF NOT EXISTS users (
     status VARCHAR(8) NOT NULL DEFAULT 'pending'
 );
 
-CREATE INDEX idx_users_name ON users(name);
+CREATE INDEX IF NOT EXISTS idx_users_name ON users(name);
 -- CREATE INDEX idx_users_phone ON users(phone);
-CREATE INDEX idx_users_created_at ON users(created_at);
-CREATE INDEX idx_users_updated_at ON users(updated_at);
-CREATE INDEX idx_users_status ON users(status);
-CREATE INDEX idx_users_role ON users(role);
-
-
--- DROP TABLE IF EXISTS users;
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
+CREATE INDEX IF NOT EXISTS idx_users_updated_at ON users(updated_at);
+CREATE INDEX IF NOT EXISTS idx_users_status ON users(status);
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
@@ -0,0 +1,3 @@
+-- Rollback roles
+
+DROP TABLE IF EXISTS roles;
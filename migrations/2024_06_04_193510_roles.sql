@@ -8,4 +8,6 @@ CREATE TABLE IF NOT EXISTS roles (
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE INDEX ON roles (name);
+-- Named as Postgres named the index when this statement had no name, so databases created before the
+-- ledger keep their index
+CREATE INDEX IF NOT EXISTS roles_name_idx ON roles (name);
@@ -0,0 +1,3 @@
+-- Rollback booking
+
+DROP TABLE IF EXISTS booking;
@@ -8,4 +8,6 @@ CREATE TABLE IF NOT EXISTS booking (
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE INDEX ON booking (name);
+-- Named as Postgres named the index when this statement had no name, so databases created before the
+-- ledger keep their index
+CREATE INDEX IF NOT EXISTS booking_name_idx ON booking (name);
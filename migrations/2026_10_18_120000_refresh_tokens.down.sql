@@ -0,0 +1,3 @@
+-- Rollback refresh_tokens
+
+DROP TABLE IF EXISTS refresh_tokens;
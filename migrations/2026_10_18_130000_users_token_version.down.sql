@@ -0,0 +1,3 @@
+-- Rollback users_token_version
+
+ALTER TABLE users DROP COLUMN IF EXISTS token_version;
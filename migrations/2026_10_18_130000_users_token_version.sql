@@ -0,0 +1,3 @@
+-- Migration users_token_version
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Rollback permissions
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP INDEX IF EXISTS idx_roles_name_unique;
@@ -0,0 +1,59 @@
+-- Migration permissions
+
+-- Roles are referenced by name, from users.role
+DELETE FROM roles extra USING roles kept WHERE extra.name = kept.name AND extra.id > kept.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_name_unique ON roles(name);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_permissions_permission_id ON role_permissions(permission_id);
+
+-- The roles of entity.Roles and the permissions of entity.Permissions, with their default grants
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Users with the admin role'),
+    ('manager', 'Users with the manager role'),
+    ('user', 'Users with the user role')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:list', 'List users'),
+    ('users:read', 'Read any user'),
+    ('users:create', 'Create users'),
+    ('users:update', 'Update any user and the status of users'),
+    ('users:delete', 'Delete users'),
+    ('users:terminate', 'Terminate users'),
+    ('roles:assign', 'Give users a role')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM (VALUES
+    ('admin', 'users:list'),
+    ('admin', 'users:read'),
+    ('admin', 'users:create'),
+    ('admin', 'users:update'),
+    ('admin', 'users:delete'),
+    ('admin', 'users:terminate'),
+    ('admin', 'roles:assign'),
+    ('manager', 'users:list'),
+    ('manager', 'users:read'),
+    ('manager', 'users:create'),
+    ('manager', 'users:update'),
+    ('manager', 'users:terminate')
+) AS grants (role, permission)
+JOIN roles ON roles.name = grants.role
+JOIN permissions ON permissions.name = grants.permission
+ON CONFLICT DO NOTHING;
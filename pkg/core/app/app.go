@@ -11,10 +11,12 @@ import (
 	"syscall" // Add this import
 	"time"
 
+	"github.com/JubaerHossain/rootx/pkg/core/auth"
 	"github.com/JubaerHossain/rootx/pkg/core/cache"
 	"github.com/JubaerHossain/rootx/pkg/core/config"
 	"github.com/JubaerHossain/rootx/pkg/core/database"
 	"github.com/JubaerHossain/rootx/pkg/core/logger"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -27,6 +29,7 @@ type App struct {
 	PublicFS     fs.FS
 	Cache        cache.CacheService
 	DB           *pgxpool.Pool
+	Tx           *database.TxManager
 	Logger       *zap.Logger
 }
 
@@ -47,6 +50,16 @@ func StartApp() (*App, error) {
 		return nil, fmt.Errorf("error loading config: %w", err)
 	}
 
+	// Load the token signing keys, production refuses to start without them
+	if err := auth.Init(); err != nil {
+		return nil, fmt.Errorf("error initializing token signing: %w", err)
+	}
+
+	// Load the pagination cursor secret, production refuses to start without it
+	if err := utilQuery.InitCursor(config.GlobalConfig); err != nil {
+		return nil, fmt.Errorf("error initializing cursor signing: %w", err)
+	}
+
 	// Initialize database and cache asynchronously
 	dbPool, err := initDatabase()
 	if err != nil {
@@ -65,6 +78,7 @@ func StartApp() (*App, error) {
 		BuildVersion: config.GlobalConfig.AppEnv,
 		Cache:        cacheService,
 		DB:           dbPool,
+		Tx:           database.NewTxManager(dbPool),
 		Logger:       logger.Logger,
 	}
 
@@ -0,0 +1,169 @@
+package appError
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Kind classifies an error so transports can answer it the same way, e.g. with an HTTP status
+type Kind string
+
+const (
+	BadRequest   Kind = "bad_request"
+	NotFound     Kind = "not_found"
+	Conflict     Kind = "conflict"
+	Validation   Kind = "validation"
+	Unauthorized Kind = "unauthorized"
+	Forbidden    Kind = "forbidden"
+	Internal     Kind = "internal"
+)
+
+// Error is an error of the domain with a kind and a stable, machine readable code such as "user_not_found"
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Err is the underlying error, it is never shown to clients
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error of a kind
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap creates an error of a kind caused by err
+func Wrap(kind Kind, code, message string, err error) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
+
+// NewNotFound creates a NotFound error, e.g. NewNotFound("user_not_found", "user not found")
+func NewNotFound(code, message string) *Error {
+	return New(NotFound, code, message)
+}
+
+// NewConflict creates a Conflict error
+func NewConflict(code, message string) *Error {
+	return New(Conflict, code, message)
+}
+
+// NewValidation creates a Validation error
+func NewValidation(code, message string) *Error {
+	return New(Validation, code, message)
+}
+
+// NewUnauthorized creates an Unauthorized error
+func NewUnauthorized(code, message string) *Error {
+	return New(Unauthorized, code, message)
+}
+
+// NewForbidden creates a Forbidden error
+func NewForbidden(code, message string) *Error {
+	return New(Forbidden, code, message)
+}
+
+// NewBadRequest creates a BadRequest error
+func NewBadRequest(code, message string) *Error {
+	return New(BadRequest, code, message)
+}
+
+// NewInternal wraps an unexpected error
+func NewInternal(err error) *Error {
+	return Wrap(Internal, "internal_error", "internal server error", err)
+}
+
+// As returns the Error in the chain of err, an Internal error wrapping err when there is none
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return NewInternal(err)
+}
+
+// KindOf returns the kind of err, Internal when it is not an Error
+func KindOf(err error) Kind {
+	return As(err).Kind
+}
+
+// Is reports whether err is an Error of the kind
+func Is(err error, kind Kind) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Kind == kind
+}
+
+// statuses are the HTTP statuses of the kinds
+var statuses = map[Kind]int{
+	BadRequest:   http.StatusBadRequest,
+	NotFound:     http.StatusNotFound,
+	Conflict:     http.StatusConflict,
+	Validation:   http.StatusUnprocessableEntity,
+	Unauthorized: http.StatusUnauthorized,
+	Forbidden:    http.StatusForbidden,
+	Internal:     http.StatusInternalServerError,
+}
+
+// HTTPStatus returns the HTTP status of an error, 500 when it is not an Error
+func HTTPStatus(err error) int {
+	if status, ok := statuses[KindOf(err)]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// keyDetail extracts the column of a constraint violation detail, e.g. Key (phone)=(01700000001) already exists.
+var keyDetail = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// FromDB translates a database error: no rows becomes NotFound, a unique violation Conflict,
+// a foreign key violation Validation on insert or update and Conflict on delete. Other errors
+// become Internal, and an Error is returned as is.
+func FromDB(err error) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Wrap(NotFound, "not_found", "record not found", err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return NewInternal(err)
+	}
+	column := "record"
+	if match := keyDetail.FindStringSubmatch(pgErr.Detail); match != nil {
+		column = match[1]
+	}
+
+	switch pgErr.Code {
+	case "23505":
+		return Wrap(Conflict, "already_exists", fmt.Sprintf("%s already exists", column), err)
+	case "23503":
+		// Deleting or updating a row that other rows still reference
+		if strings.HasPrefix(pgErr.Message, "update or delete on table") {
+			return Wrap(Conflict, "still_referenced", fmt.Sprintf("%s is still referenced", column), err)
+		}
+		return Wrap(Validation, "reference_not_found", fmt.Sprintf("%s does not exist", column), err)
+	}
+	return NewInternal(err)
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -13,81 +15,105 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func CreateToken(payload interface{}) (string, error) {
-	// Create the JWT claims
-	jwtTime := config.GlobalConfig.JwtExpiration
-	if jwtTime == "" {
-		jwtTime = "24"
+// CreateToken signs an access token of the user carrying its token version, and returns it with its
+// expiry. Each token has a unique ID (jti) so it can be revoked on its own.
+func CreateToken(user *userEntity.AuthUser, tokenVersion int) (string, time.Time, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claims := newClaims(user, tokenVersion, id, time.Now())
+
+	// Sign the token with the current key
+	s, err := currentSigner()
+	if err != nil {
+		return "", time.Time{}, err
 	}
-	expiration, err := strconv.Atoi(jwtTime)
+	tokenString, err := s.Sign(claims)
 	if err != nil {
-		expiration = 24
+		return "", time.Time{}, fmt.Errorf("failed to generate JWT token: %v", err)
 	}
 
-	claims := jwt.MapClaims{
-		"user": payload,
-		"exp":  time.Now().Add(time.Hour * time.Duration(expiration)).Unix(), // Token expiration time
+	return tokenString, claims.ExpiresAt.Time, nil
+}
+
+// newTokenID returns 16 random bytes in hex
+func newTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
 	}
+	return hex.EncodeToString(id), nil
+}
 
-	// Create the JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// AccessTokenTTL is the lifetime of access tokens, JWT_EXPIRATION as a duration such as "1h" or
+// as a number of hours, 24 hours by default
+func AccessTokenTTL() time.Duration {
+	return ttl(config.GlobalConfig.JwtExpiration, 24*time.Hour)
+}
 
-	// Sign the token with a secret key
-	secretKey := config.GlobalConfig.JwtSecretKey
-	if secretKey == "" {
-		secretKey = "your-secret"
+// ttl parses a duration such as "15m" or a number of hours such as "24", fallback when it is empty or invalid
+func ttl(value string, fallback time.Duration) time.Duration {
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return duration
 	}
-	tokenString, err := token.SignedString([]byte(secretKey))
-	if err != nil {
-		return "", fmt.Errorf("failed to generate JWT token: %v", err)
+	if hours, err := strconv.Atoi(value); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
 	}
+	return fallback
+}
 
-	return tokenString, nil
+// Token is a verified access token
+type Token struct {
+	// ID is the jti claim
+	ID        string
+	Version   int
+	ExpiresAt time.Time
+	User      *userEntity.AuthUser
 }
 
-// VerifyToken verifies the JWT token
-func VerifyToken(tokenString string) (bool, *userEntity.AuthUser, error) {
+// ParseToken verifies the signature and the claims of an access token, see Claims.Validate, with or
+// without its "Bearer " prefix
+func ParseToken(tokenString string) (*Token, error) {
 	// Remove the "Bearer " prefix from the token string
 	tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-	// Parse the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Check the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		// Return the secret key
-		secretKey := config.GlobalConfig.JwtSecretKey
-		if secretKey == "" {
-			secretKey = "your-secret"
-		}
-		return []byte(secretKey), nil
-	})
-
+	s, err := currentSigner()
 	if err != nil {
-		return false, nil, fmt.Errorf("failed to parse token: %v", err)
+		return nil, err
 	}
-
-	// Check if the token is valid
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// Extract user information from claims
-		if userClaim, exists := claims["user"]; exists {
-			// Extract user information from claims
-			if userData, ok := userClaim.(map[string]interface{}); ok {
-				// Deserialize user data from the map
-				user := userEntity.AuthUser{
-					ID:       uint(int(userData["id"].(float64))),
-					Name: userData["name"].(string),
-					Phone:    userData["phone"].(string),
-					Role:     entity.Role(userData["role"].(string)),
-					Status:   entity.Status(userData["status"].(string)),
-				}
-				return true, &user, nil
-			}
-		}
+	// Parse the token, the signer checks its key and signing method
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.Keyfunc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %v", err)
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	return false, nil, nil
+	// Validate checked the subject
+	id, _ := claims.UserID()
+	return &Token{
+		ID:        claims.ID,
+		Version:   claims.Version,
+		ExpiresAt: claims.ExpiresAt.Time,
+		User: &userEntity.AuthUser{
+			ID:     id,
+			Name:   claims.User.Name,
+			Phone:  claims.User.Phone,
+			Role:   claims.User.Role,
+			Status: claims.User.Status,
+		},
+	}, nil
+}
+
+// VerifyToken verifies the JWT token, see ParseToken
+func VerifyToken(tokenString string) (bool, *userEntity.AuthUser, error) {
+	token, err := ParseToken(tokenString)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, token.User, nil
 }
 
 func User(r *http.Request) (*userEntity.AuthUser, error) {
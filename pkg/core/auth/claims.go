@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	userEntity "github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Defaults of the issuer, audience and clock skew leeway of the access tokens
+const (
+	DefaultIssuer   = "rootx"
+	DefaultAudience = "rootx-api"
+	DefaultLeeway   = 30 * time.Second
+)
+
+// Claims are the claims of an access token. The subject is the user ID, and User only carries what
+// the handlers read from the authenticated user, never the password or anything else secret.
+type Claims struct {
+	jwt.RegisteredClaims
+	User    UserClaims `json:"user"`
+	Version int        `json:"ver"`
+}
+
+// UserClaims is the user payload of an access token
+type UserClaims struct {
+	Name   string        `json:"name"`
+	Phone  string        `json:"phone"`
+	Role   entity.Role   `json:"role"`
+	Status entity.Status `json:"status"`
+}
+
+// newClaims returns the claims of a token of the user issued at now
+func newClaims(user *userEntity.AuthUser, tokenVersion int, id string, now time.Time) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Issuer:    Issuer(),
+			Audience:  jwt.ClaimStrings{Audience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+			ID:        id,
+		},
+		User: UserClaims{
+			Name:   user.Name,
+			Phone:  user.Phone,
+			Role:   user.Role,
+			Status: user.Status,
+		},
+		Version: tokenVersion,
+	}
+}
+
+// Valid checks the claims at the current time, see Validate. The parser calls it for every token.
+func (c *Claims) Valid() error {
+	return c.Validate(time.Now(), Leeway())
+}
+
+// Validate checks the expiry, the not before and issued at times with leeway for the clock skew between
+// servers, the issuer and the audience, and that the token has a subject and an ID
+func (c *Claims) Validate(now time.Time, leeway time.Duration) error {
+	if !c.VerifyExpiresAt(now.Add(-leeway), true) {
+		return fmt.Errorf("token is expired")
+	}
+	if !c.VerifyNotBefore(now.Add(leeway), false) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if !c.VerifyIssuedAt(now.Add(leeway), false) {
+		return fmt.Errorf("token used before issued")
+	}
+	if !c.VerifyIssuer(Issuer(), true) {
+		return fmt.Errorf("invalid issuer %q", c.Issuer)
+	}
+	if !c.VerifyAudience(Audience(), true) {
+		return fmt.Errorf("invalid audience")
+	}
+	if _, err := c.UserID(); err != nil {
+		return err
+	}
+	if c.ID == "" {
+		return fmt.Errorf("token has no ID")
+	}
+	return nil
+}
+
+// UserID returns the user ID of the subject
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, strconv.IntSize)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid subject %q", c.Subject)
+	}
+	return uint(id), nil
+}
+
+// Issuer is the iss claim of the access tokens, JWT_ISSUER or DefaultIssuer
+func Issuer() string {
+	return orDefault(config.GlobalConfig.JwtIssuer, DefaultIssuer)
+}
+
+// Audience is the aud claim of the access tokens, JWT_AUDIENCE or DefaultAudience. Other services
+// verifying the tokens should check it as well.
+func Audience() string {
+	return orDefault(config.GlobalConfig.JwtAudience, DefaultAudience)
+}
+
+// Leeway is the clock skew tolerated when checking the token times, JWT_LEEWAY or DefaultLeeway
+func Leeway() time.Duration {
+	leeway, err := time.ParseDuration(strings.TrimSpace(config.GlobalConfig.JwtLeeway))
+	if err != nil || leeway < 0 {
+		return DefaultLeeway
+	}
+	return leeway
+}
+
+func orDefault(value, fallback string) string {
+	if value = strings.TrimSpace(value); value != "" {
+		return value
+	}
+	return fallback
+}
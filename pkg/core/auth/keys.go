@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Key is a private signing key identified by the kid header of the tokens it signs
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// ActiveFrom is when the key starts signing, the zero time for right away
+	ActiveFrom time.Time
+
+	private crypto.Signer
+}
+
+// NewKey creates a key from an RSA, ECDSA or Ed25519 private key, signing with RS256, ES256, ES384, ES512 or EdDSA
+func NewKey(id string, private crypto.Signer, activeFrom time.Time) (*Key, error) {
+	var method jwt.SigningMethod
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("key %s: RSA keys need at least 2048 bits", id)
+		}
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			method = jwt.SigningMethodES256
+		case elliptic.P384():
+			method = jwt.SigningMethodES384
+		case elliptic.P521():
+			method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("key %s: unsupported curve %s", id, k.Curve.Params().Name)
+		}
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, private)
+	}
+	return &Key{ID: id, Method: method, ActiveFrom: activeFrom, private: private}, nil
+}
+
+// LoadKey reads a PEM encoded private key, PKCS #8, PKCS #1 (RSA) or SEC 1 (EC)
+func LoadKey(id, path string, activeFrom time.Time) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", id, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: %s is not PEM encoded", id, path)
+	}
+
+	var private any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", id, err)
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, private)
+	}
+	return NewKey(id, signer, activeFrom)
+}
+
+// ParseKeys loads the keys of JWT_KEYS, a comma separated list of kid=path entries with an optional
+// activation time, e.g. "2026-10=keys/2026-10.pem, 2026-11=keys/2026-11.pem@2026-11-01T00:00:00Z"
+func ParseKeys(value string) ([]*Key, error) {
+	keys := []*Key{}
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, path, ok := strings.Cut(entry, "=")
+		id, path = strings.TrimSpace(id), strings.TrimSpace(path)
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid=path[@activation time]", entry)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate key ID %q in JWT_KEYS", id)
+		}
+		seen[id] = true
+
+		var activeFrom time.Time
+		path, at, dated := strings.Cut(path, "@")
+		if dated {
+			parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(at))
+			if err != nil {
+				return nil, fmt.Errorf("invalid activation time of key %s: %w", id, err)
+			}
+			activeFrom = parsed
+		}
+
+		key, err := LoadKey(id, strings.TrimSpace(path), activeFrom)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// KeySet signs with the latest active key and verifies with every key that is not retired. A key retires
+// a grace period after the next key starts signing, or after the set was loaded when the next key is undated,
+// so the tokens it signed keep working until they expire. Keys that activate later are published in advance.
+type KeySet struct {
+	// keys are sorted by activation time
+	keys     []*Key
+	grace    time.Duration
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+// NewKeySet creates the key set of keys, grace should be at least the lifetime of the access tokens
+func NewKeySet(keys []*Key, grace time.Duration) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signing key")
+	}
+	sorted := append([]*Key{}, keys...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ActiveFrom.Before(sorted[j].ActiveFrom) })
+	return &KeySet{keys: sorted, grace: grace, loadedAt: time.Now(), now: time.Now}, nil
+}
+
+// current returns the key signing at now, the latest activated one
+func (s *KeySet) current(now time.Time) (*Key, error) {
+	var current *Key
+	for _, key := range s.keys {
+		if !key.ActiveFrom.After(now) {
+			current = key
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("no signing key is active yet, the first activates at %s", s.keys[0].ActiveFrom.Format(time.RFC3339))
+	}
+	return current, nil
+}
+
+// valid returns the keys that are not retired at now
+func (s *KeySet) valid(now time.Time) []*Key {
+	keys := []*Key{}
+	for i, key := range s.keys {
+		if i+1 < len(s.keys) {
+			// An undated key has no activation time to count the grace from, it replaced the key when the set was loaded
+			replacedAt := s.keys[i+1].ActiveFrom
+			if replacedAt.IsZero() {
+				replacedAt = s.loadedAt
+			}
+			if !now.Before(replacedAt.Add(s.grace)) {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Sign signs the claims with the current key, named by the kid header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key, err := s.current(s.now())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Keyfunc returns the public key named by the kid header of the token, if it is not retired
+// and the token uses its algorithm
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range s.valid(s.now()) {
+		if key.ID != kid {
+			continue
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v for key %s", token.Header["alg"], kid)
+		}
+		return key.private.Public(), nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// JWKS returns the public keys that are not retired
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range s.valid(s.now()) {
+		set.Keys = append(set.Keys, key.JWK())
+	}
+	return set
+}
+
+// JWKS is a JSON Web Key Set (RFC 7517)
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is the public part of a key as a JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWK returns the public key as a JSON Web Key
+func (k *Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64URL(public.N.Bytes())
+		jwk.E = base64URL(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// Coordinates are padded to the size of the curve
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = base64URL(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64URL(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64URL(public)
+	}
+	return jwk
+}
+
+func base64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
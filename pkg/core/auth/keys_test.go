@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestKeyJWK(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+
+	tests := []struct {
+		name    string
+		private crypto.Signer
+		wantKty string
+		wantAlg string
+		wantCrv string
+		// wantSize is the length of the decoded coordinates, 0 for RSA keys
+		wantSize int
+	}{
+		{name: "RSA", private: rsaKey, wantKty: "RSA", wantAlg: "RS256"},
+		{name: "P-256", private: ecKey(t, elliptic.P256()), wantKty: "EC", wantAlg: "ES256", wantCrv: "P-256", wantSize: 32},
+		{name: "P-384", private: ecKey(t, elliptic.P384()), wantKty: "EC", wantAlg: "ES384", wantCrv: "P-384", wantSize: 48},
+		{name: "P-521", private: ecKey(t, elliptic.P521()), wantKty: "EC", wantAlg: "ES512", wantCrv: "P-521", wantSize: 66},
+		{name: "Ed25519", private: ed25519Key, wantKty: "OKP", wantAlg: "EdDSA", wantCrv: "Ed25519", wantSize: ed25519.PublicKeySize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := NewKey("kid-"+tt.name, tt.private, time.Time{})
+			if err != nil {
+				t.Fatalf("NewKey() error = %v", err)
+			}
+			jwk := key.JWK()
+			if jwk.Kid != "kid-"+tt.name || jwk.Use != "sig" || jwk.Kty != tt.wantKty || jwk.Alg != tt.wantAlg || jwk.Crv != tt.wantCrv {
+				t.Errorf("JWK() = %+v, want kid-%s sig %s %s %q", jwk, tt.name, tt.wantKty, tt.wantAlg, tt.wantCrv)
+			}
+
+			switch public := tt.private.Public().(type) {
+			case *rsa.PublicKey:
+				if n := decodeBase64URL(t, jwk.N); new(big.Int).SetBytes(n).Cmp(public.N) != 0 {
+					t.Error("n is not the modulus")
+				}
+				if e := decodeBase64URL(t, jwk.E); new(big.Int).SetBytes(e).Int64() != int64(public.E) {
+					t.Errorf("e = %s, want the exponent %d", jwk.E, public.E)
+				}
+				if jwk.X != "" || jwk.Y != "" {
+					t.Errorf("RSA key has coordinates %q %q", jwk.X, jwk.Y)
+				}
+			case *ecdsa.PublicKey:
+				x, y := decodeBase64URL(t, jwk.X), decodeBase64URL(t, jwk.Y)
+				if len(x) != tt.wantSize || len(y) != tt.wantSize {
+					t.Errorf("coordinates are %d and %d bytes, want %d", len(x), len(y), tt.wantSize)
+				}
+				if new(big.Int).SetBytes(x).Cmp(public.X) != 0 || new(big.Int).SetBytes(y).Cmp(public.Y) != 0 {
+					t.Error("x and y are not the public point")
+				}
+			case ed25519.PublicKey:
+				if x := decodeBase64URL(t, jwk.X); len(x) != tt.wantSize || !public.Equal(ed25519.PublicKey(x)) {
+					t.Errorf("x = %s, want the public key", jwk.X)
+				}
+				if jwk.Y != "" {
+					t.Errorf("Ed25519 key has y %q", jwk.Y)
+				}
+			}
+		})
+	}
+}
+
+func TestNewKeyErrors(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		private crypto.Signer
+		wantErr string
+	}{
+		{name: "small RSA key", private: small, wantErr: "at least 2048 bits"},
+		{name: "unsupported curve", private: ecKey(t, elliptic.P224()), wantErr: "unsupported curve P-224"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKey("kid", tt.private, time.Time{}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewKey() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	loadedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	grace := 15 * time.Minute
+
+	tests := []struct {
+		name string
+		// keys activate at an offset from loadedAt, or are undated
+		keys        []testKey
+		at          time.Duration
+		wantCurrent string
+		wantValid   string
+	}{
+		{
+			name:        "single undated key",
+			keys:        []testKey{{id: "a"}},
+			at:          24 * time.Hour,
+			wantCurrent: "a",
+			wantValid:   "a",
+		},
+		{
+			name:        "next key is published before it activates",
+			keys:        []testKey{{id: "a"}, {id: "b", at: offset(time.Hour)}},
+			at:          30 * time.Minute,
+			wantCurrent: "a",
+			wantValid:   "a,b",
+		},
+		{
+			name:        "replaced key verifies during the grace",
+			keys:        []testKey{{id: "a"}, {id: "b", at: offset(time.Hour)}},
+			at:          time.Hour + 10*time.Minute,
+			wantCurrent: "b",
+			wantValid:   "a,b",
+		},
+		{
+			name:        "replaced key retires after the grace",
+			keys:        []testKey{{id: "a"}, {id: "b", at: offset(time.Hour)}},
+			at:          time.Hour + grace,
+			wantCurrent: "b",
+			wantValid:   "b",
+		},
+		{
+			name:        "key replaced before the load retires on the activation time of the next key",
+			keys:        []testKey{{id: "a"}, {id: "b", at: offset(-10 * time.Minute)}},
+			at:          5 * time.Minute,
+			wantCurrent: "b",
+			wantValid:   "b",
+		},
+		{
+			name:        "key replaced by an undated key verifies for the grace after the load",
+			keys:        []testKey{{id: "a"}, {id: "b"}},
+			at:          10 * time.Minute,
+			wantCurrent: "b",
+			wantValid:   "a,b",
+		},
+		{
+			name:        "key replaced by an undated key retires after the grace",
+			keys:        []testKey{{id: "a"}, {id: "b"}},
+			at:          grace,
+			wantCurrent: "b",
+			wantValid:   "b",
+		},
+		{
+			name:        "keys are sorted by activation time",
+			keys:        []testKey{{id: "c", at: offset(2 * time.Hour)}, {id: "a"}, {id: "b", at: offset(time.Hour)}},
+			at:          time.Hour + 5*time.Minute,
+			wantCurrent: "b",
+			wantValid:   "a,b,c",
+		},
+		{
+			name:        "no key is active yet",
+			keys:        []testKey{{id: "a", at: offset(time.Hour)}},
+			at:          0,
+			wantCurrent: "",
+			wantValid:   "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := []*Key{}
+			for _, k := range tt.keys {
+				var activeFrom time.Time
+				if k.at != nil {
+					activeFrom = loadedAt.Add(*k.at)
+				}
+				key, err := NewKey(k.id, ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)), activeFrom)
+				if err != nil {
+					t.Fatal(err)
+				}
+				keys = append(keys, key)
+			}
+			set, err := NewKeySet(keys, grace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set.loadedAt = loadedAt
+			now := loadedAt.Add(tt.at)
+			set.now = func() time.Time { return now }
+
+			current, err := set.current(now)
+			if tt.wantCurrent == "" {
+				if err == nil {
+					t.Errorf("current() = %s, want an error", current.ID)
+				}
+			} else if err != nil || current.ID != tt.wantCurrent {
+				t.Errorf("current() = %v, %v, want %s", current, err, tt.wantCurrent)
+			}
+
+			published := []string{}
+			for _, jwk := range set.JWKS().Keys {
+				published = append(published, jwk.Kid)
+			}
+			if got := strings.Join(published, ","); got != tt.wantValid {
+				t.Errorf("JWKS() kids = %s, want %s", got, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestKeySetVerify(t *testing.T) {
+	loadedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	old, err := NewKey("old", ecKey(t, elliptic.P256()), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := NewKey("next", ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)), loadedAt.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := NewKeySet([]*Key{old, next}, 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.loadedAt = loadedAt
+	now := loadedAt
+	set.now = func() time.Time { return now }
+
+	signed, err := set.Sign(jwt.RegisteredClaims{Subject: "1"})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		at      time.Duration
+		wantErr bool
+	}{
+		{name: "current key", token: signed, at: 0},
+		{name: "replaced key during the grace", token: signed, at: time.Hour + 10*time.Minute},
+		{name: "retired key", token: signed, at: 2 * time.Hour, wantErr: true},
+		{name: "unknown kid", token: withHeader(t, old, "kid", "other"), at: 0, wantErr: true},
+		{name: "algorithm of another key", token: withHeader(t, old, "alg", "none"), at: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = loadedAt.Add(tt.at)
+			_, err := jwt.Parse(tt.token, set.Keyfunc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testKey is a key of the rotation tests, activating at an offset from the load time or undated
+type testKey struct {
+	id string
+	at *time.Duration
+}
+
+func offset(d time.Duration) *time.Duration {
+	return &d
+}
+
+func ecKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// withHeader signs a token with key after overriding a header, as a forged or foreign token would
+func withHeader(t *testing.T, key *Key, name, value string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(key.Method, jwt.RegisteredClaims{Subject: "1"})
+	token.Header["kid"] = key.ID
+	token.Header[name] = value
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func decodeBase64URL(t *testing.T, value string) []byte {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatalf("%q is not base64url without padding: %v", value, err)
+	}
+	return data
+}
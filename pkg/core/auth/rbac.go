@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	userEntity "github.com/JubaerHossain/rootx/domain/entity"
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+)
+
+// ErrForbidden is answered when the role of the user does not grant a permission
+var ErrForbidden = appError.NewForbidden("forbidden", "you do not have permission to do this")
+
+// ErrHigherRole is answered when a user acts on a user whose role outranks theirs
+var ErrHigherRole = appError.NewForbidden("higher_role", "you cannot act on a user with a higher role than yours")
+
+// RolePermissions gives the permissions granted to roles
+type RolePermissions interface {
+	RolePermissions(ctx context.Context, role entity.Role) ([]entity.Permission, error)
+}
+
+// Can reports whether the role of the user grants the permission
+func Can(ctx context.Context, permissions RolePermissions, user *userEntity.AuthUser, permission entity.Permission) (bool, error) {
+	granted, err := permissions.RolePermissions(ctx, user.Role)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(granted, permission), nil
+}
+
+// Authorize returns ErrForbidden unless the role of the user grants the permission
+func Authorize(ctx context.Context, permissions RolePermissions, user *userEntity.AuthUser, permission entity.Permission) error {
+	allowed, err := Can(ctx, permissions, user, permission)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AuthorizeTarget returns ErrHigherRole when the role of the target outranks the role of the user
+func AuthorizeTarget(user *userEntity.AuthUser, target entity.Role) error {
+	if target.Outranks(user.Role) {
+		return ErrHigherRole
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	userEntity "github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+)
+
+func TestAuthorizeTarget(t *testing.T) {
+	tests := []struct {
+		user    entity.Role
+		target  entity.Role
+		wantErr bool
+	}{
+		{user: entity.AdminRole, target: entity.AdminRole},
+		{user: entity.AdminRole, target: entity.UserRole},
+		{user: entity.ManagerRole, target: entity.ManagerRole},
+		{user: entity.ManagerRole, target: entity.UserRole},
+		{user: entity.ManagerRole, target: entity.AdminRole, wantErr: true},
+		{user: entity.UserRole, target: entity.ManagerRole, wantErr: true},
+		{user: entity.UserRole, target: entity.UserRole},
+		{user: "unknown", target: entity.UserRole, wantErr: true},
+		{user: entity.UserRole, target: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.user)+" on "+string(tt.target), func(t *testing.T) {
+			err := AuthorizeTarget(&userEntity.AuthUser{Role: tt.user}, tt.target)
+			if tt.wantErr != errors.Is(err, ErrHigherRole) || (!tt.wantErr && err != nil) {
+				t.Errorf("AuthorizeTarget() error = %v, want higher role %v", err, tt.wantErr)
+			}
+		})
+	}
+}
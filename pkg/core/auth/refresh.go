@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+)
+
+// RefreshTokenTTL is the lifetime of refresh tokens, REFRESH_TOKEN_EXPIRATION, 30 days by default
+func RefreshTokenTTL() time.Duration {
+	return ttl(config.GlobalConfig.RefreshExpiration, 30*24*time.Hour)
+}
+
+// NewRefreshToken returns an opaque refresh token for the client and its hash, only the hash is stored
+func NewRefreshToken() (token string, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(secret)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the SHA-256 of a refresh token in hex, the token is random enough not to need a salt
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTokenFamily returns the ID of a new family of refresh tokens, the tokens rotated from one login
+func NewTokenFamily() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate token family: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
+	"github.com/JubaerHossain/rootx/pkg/core/cache"
+)
+
+// Errors of the revoked tokens
+var (
+	ErrTokenRevoked = appError.NewUnauthorized("token_revoked", "token has been revoked")
+	// ErrTokenOutdated is answered for a token issued before a password change, a termination or a logout-all
+	ErrTokenOutdated = appError.NewUnauthorized("token_outdated", "token is no longer valid, log in again")
+)
+
+// ErrRevocationUnavailable is returned when a token cannot be revoked because there is no cache to hold the list
+var ErrRevocationUnavailable = errors.New("token revocation needs a cache, set IS_REDIS=true")
+
+// revokedTokenPrefix prefixes the cache keys of the revoked token IDs
+const revokedTokenPrefix = "revoked_token:"
+
+// TokenVersions gives the current token version of users. Each password change, termination and logout-all
+// increments it, so the tokens carrying an older version are rejected.
+type TokenVersions interface {
+	TokenVersion(ctx context.Context, userID uint) (int, error)
+}
+
+// RevokeToken adds the ID of a token to the revocation list in the cache until the token expires.
+// It fails with ErrRevocationUnavailable rather than pretending to revoke when the cache has no store.
+func RevokeToken(ctx context.Context, cacheService cache.CacheService, token *Token) error {
+	if !cache.Available(cacheService) {
+		return appError.NewInternal(ErrRevocationUnavailable)
+	}
+	remaining := time.Until(token.ExpiresAt)
+	if token.ID == "" || remaining <= 0 {
+		return nil
+	}
+	if err := cacheService.Set(ctx, revokedTokenPrefix+token.ID, "1", remaining); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether the token ID is on the revocation list
+func IsRevoked(ctx context.Context, cacheService cache.CacheService, tokenID string) (bool, error) {
+	if tokenID == "" || cacheService == nil {
+		return false, nil
+	}
+	value, err := cacheService.Get(ctx, revokedTokenPrefix+tokenID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return value != "", nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/JubaerHossain/rootx/pkg/core/logger"
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+)
+
+// Signer signs the access tokens and finds the keys verifying them
+type Signer interface {
+	// Sign signs the claims with the current key
+	Sign(claims jwt.Claims) (string, error)
+	// Keyfunc returns the key verifying a token, see jwt.Keyfunc
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	// JWKS returns the public keys other services verify the tokens with
+	JWKS() JWKS
+}
+
+var (
+	signer      Signer
+	signerMutex sync.RWMutex
+)
+
+// SetSigner replaces the signer of the access tokens
+func SetSigner(s Signer) {
+	signerMutex.Lock()
+	defer signerMutex.Unlock()
+	signer = s
+}
+
+// currentSigner returns the signer set by Init or SetSigner
+func currentSigner() (Signer, error) {
+	signerMutex.RLock()
+	defer signerMutex.RUnlock()
+	if signer == nil {
+		return nil, fmt.Errorf("no token signer, call auth.Init first")
+	}
+	return signer, nil
+}
+
+// Init loads the signing keys of JWT_KEYS, see ParseKeys. Without keys it signs with the JWT_SECRET_KEY
+// HMAC secret, or with a key generated for the life of the process, but it fails in production, where
+// tokens must be signed with keys other services can verify.
+func Init() error {
+	s, err := NewSigner(config.GlobalConfig)
+	if err != nil {
+		return err
+	}
+	SetSigner(s)
+	return nil
+}
+
+// NewSigner creates the signer of a configuration, see Init
+func NewSigner(cfg *config.Config) (Signer, error) {
+	if cfg.JwtKeys != "" {
+		keys, err := ParseKeys(cfg.JwtKeys)
+		if err != nil {
+			return nil, err
+		}
+		// By default a replaced key is accepted until the last token it signed expires
+		return NewKeySet(keys, ttl(cfg.JwtKeyGrace, AccessTokenTTL()))
+	}
+	if cfg.IsProduction() {
+		return nil, fmt.Errorf("JWT_KEYS is required in production, no signing key is configured")
+	}
+
+	if cfg.JwtSecretKey != "" {
+		logger.Info("Signing tokens with the JWT_SECRET_KEY HMAC secret, set JWT_KEYS to publish a JWKS")
+		return &hmacSigner{secret: []byte(cfg.JwtSecretKey)}, nil
+	}
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	key, err := NewKey("ephemeral", private, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("No JWT_KEYS nor JWT_SECRET_KEY, signing tokens with a generated key lost on restart", zap.String("kid", key.ID))
+	return NewKeySet([]*Key{key}, 0)
+}
+
+// hmacSigner signs with a shared secret (HS256), which cannot be published
+type hmacSigner struct {
+	secret []byte
+}
+
+func (s *hmacSigner) Sign(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+func (s *hmacSigner) Keyfunc(token *jwt.Token) (interface{}, error) {
+	// Check the signing method
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return s.secret, nil
+}
+
+func (s *hmacSigner) JWKS() JWKS {
+	return JWKS{Keys: []JWK{}}
+}
+
+// JWKSHandler serves the public keys of the signer, for /.well-known/jwks.json
+func JWKSHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		set := JWKS{Keys: []JWK{}}
+		if s, err := currentSigner(); err == nil {
+			set = s.JWKS()
+		}
+
+		// Verifiers refetch the set when they meet an unknown kid, a short cache is enough
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		if err := json.NewEncoder(w).Encode(set); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
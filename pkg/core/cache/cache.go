@@ -19,6 +19,14 @@ type CacheService interface {
 	Close() error
 }
 
+// Available reports whether the cache is backed by a store, it is not when IS_REDIS=false
+func Available(cacheService CacheService) bool {
+	if svc, ok := cacheService.(*RedisCacheService); ok {
+		return svc != nil && svc.client != nil
+	}
+	return cacheService != nil
+}
+
 // RedisCacheService implements CacheService using Redis
 type RedisCacheService struct {
 	client *redis.Client
@@ -2,33 +2,45 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	AppEnv            string `mapstructure:"APP_ENV"`
-	AppPort           string `mapstructure:"APP_PORT"`
-	DBType            string `mapstructure:"DB_TYPE"`
-	DBHost            string `mapstructure:"DB_HOST"`
-	DBPort            int    `mapstructure:"DB_PORT"`
-	DBName            string `mapstructure:"DB_NAME"`
-	DBUser            string `mapstructure:"DB_USER"`
-	DBPassword        string `mapstructure:"DB_PASSWORD"`
-	DBSSLMode         string `mapstructure:"DB_SSLMODE"`
-	Migrate           bool   `mapstructure:"MIGRATE"`
-	Seed              bool   `mapstructure:"SEED"`
-	RedisExp          int    `mapstructure:"REDIS_EXP"`
-	RedisURI          string `mapstructure:"REDIS_URI"`
-	RedisPassword     string `mapstructure:"REDIS_PASSWORD"`
-	RedisDB           int    `mapstructure:"REDIS_DB"`
-	IsRedis           bool   `mapstructure:"IS_REDIS"`
-	RateLimitEnabled  bool   `mapstructure:"RATE_LIMIT_ENABLED"`
-	RateLimit         int    `mapstructure:"RATE_LIMIT"`
-	RateLimitDuration string `mapstructure:"RATE_LIMIT_DURATION"`
-	JwtSecretKey      string `mapstructure:"JWT_SECRET_KEY"`
-	JwtExpiration     string `mapstructure:"JWT_EXPIRATION"`
+	AppEnv               string `mapstructure:"APP_ENV"`
+	AppPort              string `mapstructure:"APP_PORT"`
+	DBType               string `mapstructure:"DB_TYPE"`
+	DBHost               string `mapstructure:"DB_HOST"`
+	DBPort               int    `mapstructure:"DB_PORT"`
+	DBName               string `mapstructure:"DB_NAME"`
+	DBUser               string `mapstructure:"DB_USER"`
+	DBPassword           string `mapstructure:"DB_PASSWORD"`
+	DBSSLMode            string `mapstructure:"DB_SSLMODE"`
+	Migrate              bool   `mapstructure:"MIGRATE"`
+	MigrationLockTimeout string `mapstructure:"MIGRATION_LOCK_TIMEOUT"`
+	Seed                 bool   `mapstructure:"SEED"`
+	SQLSource            string `mapstructure:"SQL_SOURCE"`
+	RedisExp             int    `mapstructure:"REDIS_EXP"`
+	RedisURI             string `mapstructure:"REDIS_URI"`
+	RedisPassword        string `mapstructure:"REDIS_PASSWORD"`
+	RedisDB              int    `mapstructure:"REDIS_DB"`
+	IsRedis              bool   `mapstructure:"IS_REDIS"`
+	RateLimitEnabled     bool   `mapstructure:"RATE_LIMIT_ENABLED"`
+	RateLimit            int    `mapstructure:"RATE_LIMIT"`
+	RateLimitDuration    string `mapstructure:"RATE_LIMIT_DURATION"`
+	JwtSecretKey         string `mapstructure:"JWT_SECRET_KEY"`
+	JwtExpiration        string `mapstructure:"JWT_EXPIRATION"`
+	JwtKeys              string `mapstructure:"JWT_KEYS"`
+	JwtIssuer            string `mapstructure:"JWT_ISSUER"`
+	JwtAudience          string `mapstructure:"JWT_AUDIENCE"`
+	JwtLeeway            string `mapstructure:"JWT_LEEWAY"`
+	JwtKeyGrace          string `mapstructure:"JWT_KEY_GRACE"`
+	RefreshExpiration    string `mapstructure:"REFRESH_TOKEN_EXPIRATION"`
+	CursorSecret         string `mapstructure:"CURSOR_SECRET"`
+	ErrorFormat          string `mapstructure:"ERROR_FORMAT"`
+	ProblemTypeBase      string `mapstructure:"PROBLEM_TYPE_BASE"`
 }
 
 var (
@@ -73,6 +85,15 @@ func LoadConfig() error {
 	return nil
 }
 
+// IsProduction reports whether APP_ENV is prod or production
+func (c *Config) IsProduction() bool {
+	switch strings.ToLower(strings.TrimSpace(c.AppEnv)) {
+	case "prod", "production":
+		return true
+	}
+	return false
+}
+
 // setDefaultValues sets default values for configuration fields
 func setDefaultValues(cfg *Config) {
 	if cfg.RedisDB == 0 {
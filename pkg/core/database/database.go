@@ -4,16 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"time"
 
+	"github.com/JubaerHossain/rootx"
 	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/JubaerHossain/rootx/pkg/core/database/migration"
+	"github.com/JubaerHossain/rootx/pkg/core/database/seed"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PgxDatabaseService struct {
-	pool *pgxpool.Pool
+	pool       *pgxpool.Pool
+	migrations migration.MigrationSource
+	seeds      migration.MigrationSource
 }
 
 // NewPgxDatabaseService initializes a new database service using pgxpool
@@ -53,7 +56,17 @@ func NewPgxDatabaseService() (*PgxDatabaseService, error) {
 		}
 	}
 
-	return &PgxDatabaseService{pool: pool}, nil
+	// Read the SQL files compiled into the binary unless SQL_SOURCE=disk
+	migrations, err := migration.NewSource(cfg.SQLSource, rootx.Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	seeds, err := migration.NewSource(cfg.SQLSource, rootx.Seeds, "seeds")
+	if err != nil {
+		return nil, err
+	}
+
+	return &PgxDatabaseService{pool: pool, migrations: migrations, seeds: seeds}, nil
 }
 
 func (db *PgxDatabaseService) GetPool() *pgxpool.Pool {
@@ -65,81 +78,58 @@ func (db *PgxDatabaseService) Close() {
 	log.Println("database connection closed")
 }
 
+// Migrate applies the pending migrations, waiting
+// for other instances that are migrating the same database
 func (db *PgxDatabaseService) Migrate() error {
-	return db.executeSQLFiles("migrations")
+	_, err := db.migrator().Up(context.Background())
+	return err
 }
 
-func (db *PgxDatabaseService) executeSQLFiles(directory string) error {
-	entries, err := os.ReadDir(directory)
-	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		filePath := filepath.Join(directory, entry.Name())
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", filePath, err)
-		}
+// migrator creates a migrator configured with the lock timeout from MIGRATION_LOCK_TIMEOUT
+func (db *PgxDatabaseService) migrator() *migration.Migrator {
+	return migration.NewMigrator(db.pool, db.migrations).WithLockTimeout(db.lockTimeout())
+}
 
-		_, err = db.pool.Exec(context.Background(), string(content))
-		if err != nil {
-			return fmt.Errorf("failed to execute file %s: %w", filePath, err)
-		}
+// lockTimeout returns MIGRATION_LOCK_TIMEOUT or the default timeout when it is not set
+func (db *PgxDatabaseService) lockTimeout() time.Duration {
+	lockTimeout, err := time.ParseDuration(config.GlobalConfig.MigrationLockTimeout)
+	if err != nil {
+		return migration.DefaultLockTimeout
 	}
-
-	log.Printf("%s files executed successfully", directory)
-	return nil
+	return lockTimeout
 }
 
-func (db *PgxDatabaseService) Seed() error {
-	return db.ExecuteSeeders("seeds")
+// MigrationStatus lists every migration with its applied state, checksum match
+// and the ledger entries whose file is missing on disk
+func (db *PgxDatabaseService) MigrationStatus() ([]*migration.Status, error) {
+	return db.migrator().Status(context.Background())
 }
 
-func (db *PgxDatabaseService) ExecuteSeeders(directory string) error {
-	entries, err := os.ReadDir(directory)
+// SchemaDrift compares the live schema against the committed snapshot and returns the differences
+func (db *PgxDatabaseService) SchemaDrift() ([]string, error) {
+	expected, err := migration.LoadSourceSnapshot(db.migrations)
 	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
+		return nil, err
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		filePath := filepath.Join(directory, entry.Name())
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", filePath, err)
-		}
-
-		tx, err := db.pool.Begin(context.Background())
-		if err != nil {
-			return fmt.Errorf("failed to begin transaction: %w", err)
-		}
-		defer func() {
-			if err != nil {
-				tx.Rollback(context.Background())
-				return
-			}
-			err = tx.Commit(context.Background())
-			if err != nil {
-				fmt.Println("Error committing transaction for file", filePath, ":", err)
-			}
-		}()
-
-		_, err = tx.Exec(context.Background(), string(content))
-		if err != nil {
-			return fmt.Errorf("failed to execute file %s: %w", filePath, err)
-		}
+	actual, err := migration.InspectSchema(context.Background(), db.pool)
+	if err != nil {
+		return nil, err
 	}
+	return migration.Diff(expected, actual), nil
+}
+
+// Seed runs the shared seeders and those of APP_ENV that have not run yet
+func (db *PgxDatabaseService) Seed() error {
+	return db.ExecuteSeeders(db.seeds)
+}
 
-	log.Printf("%s files executed successfully", directory)
-	return nil
+// ExecuteSeeders runs the pending seeders of the source, each in its own transaction
+func (db *PgxDatabaseService) ExecuteSeeders(source migration.MigrationSource) error {
+	_, err := seed.NewRunner(db.pool, source, config.GlobalConfig.AppEnv).
+		WithLockTimeout(db.lockTimeout()).
+		Run(context.Background(), seed.RunOptions{})
+	return err
 }
 
 // PoolStats returns the statistics of the connection pool
@@ -0,0 +1,132 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DB is the connection a factory inserts with, satisfied by *pgxpool.Pool, *pgx.Conn and pgx.Tx
+type DB interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+var errNoDB = errors.New("factory has no database, call On with a pool or a transaction")
+
+// Definition describes how a factory builds and stores an entity
+type Definition[T any] struct {
+	// Table is the table the entities are inserted into
+	Table string
+	// Columns are the columns filled by Row
+	Columns []string
+	// Make builds an entity with fake data
+	Make func(fake *Faker) T
+	// Prepare is called before the entity is inserted, e.g. to hash a password, it is optional
+	Prepare func(entity *T) error
+	// Row returns the values of the entity in the order of Columns
+	Row func(entity *T) []any
+}
+
+// Factory builds entities with fake data and bulk inserts them
+type Factory[T any] struct {
+	definition Definition[T]
+	states     []func(*T)
+	fake       *Faker
+	db         DB
+}
+
+// New creates a factory from a definition
+func New[T any](definition Definition[T]) *Factory[T] {
+	return &Factory[T]{definition: definition, fake: NewFaker(0)}
+}
+
+// With returns a factory that applies the given states to every entity it builds,
+// e.g. factory.User().With(factory.UserRole(entity.AdminRole))
+func (f *Factory[T]) With(states ...func(*T)) *Factory[T] {
+	clone := *f
+	clone.states = append(append([]func(*T){}, f.states...), states...)
+	return &clone
+}
+
+// On returns a factory that inserts with the given connection or transaction
+func (f *Factory[T]) On(db DB) *Factory[T] {
+	clone := *f
+	clone.db = db
+	return &clone
+}
+
+// Seed returns a factory whose fake data is reproducible, useful in tests
+func (f *Factory[T]) Seed(seed uint64) *Factory[T] {
+	clone := *f
+	clone.fake = NewFaker(seed)
+	return &clone
+}
+
+// Make builds an entity without inserting it
+func (f *Factory[T]) Make() T {
+	entity := f.definition.Make(f.fake)
+	for _, state := range f.states {
+		state(&entity)
+	}
+	return entity
+}
+
+// MakeN builds n entities without inserting them
+func (f *Factory[T]) MakeN(n int) []T {
+	entities := make([]T, 0, max(n, 0))
+	for i := 0; i < n; i++ {
+		entities = append(entities, f.Make())
+	}
+	return entities
+}
+
+// Create builds an entity and inserts it
+func (f *Factory[T]) Create(ctx context.Context) (T, error) {
+	entities, err := f.CreateN(ctx, 1)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return entities[0], nil
+}
+
+// CreateN builds n entities and inserts them with a single COPY.
+// The returned entities hold the inserted values but not the generated IDs.
+func (f *Factory[T]) CreateN(ctx context.Context, n int) ([]T, error) {
+	if f.db == nil {
+		return nil, errNoDB
+	}
+	entities := f.MakeN(n)
+	if err := f.Insert(ctx, entities); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// Insert inserts entities built with Make or MakeN with a single COPY, e.g. after replacing values
+// that would conflict with existing rows. The entities are prepared in place.
+func (f *Factory[T]) Insert(ctx context.Context, entities []T) error {
+	if f.db == nil {
+		return errNoDB
+	}
+	if len(entities) == 0 {
+		return nil
+	}
+
+	rows := make([][]any, 0, len(entities))
+	for i := range entities {
+		if f.definition.Prepare != nil {
+			if err := f.definition.Prepare(&entities[i]); err != nil {
+				return fmt.Errorf("failed to prepare %s row: %w", f.definition.Table, err)
+			}
+		}
+		rows = append(rows, f.definition.Row(&entities[i]))
+	}
+
+	if _, err := f.db.CopyFrom(ctx, pgx.Identifier{f.definition.Table}, f.definition.Columns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("failed to insert %d %s rows: %w", len(rows), f.definition.Table, err)
+	}
+	return nil
+}
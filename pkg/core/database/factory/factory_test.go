@@ -0,0 +1,186 @@
+package factory
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	userEntity "github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/validation"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+	"github.com/jackc/pgx/v5"
+)
+
+// copyRecorder is a DB recording what it is asked to copy
+type copyRecorder struct {
+	table   pgx.Identifier
+	columns []string
+	rows    [][]any
+}
+
+func (c *copyRecorder) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	c.table, c.columns = tableName, columnNames
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return 0, err
+		}
+		c.rows = append(c.rows, values)
+	}
+	return int64(len(c.rows)), rowSrc.Err()
+}
+
+func TestUserFactoryMakesValidUsers(t *testing.T) {
+	phones := map[string]bool{}
+	for _, user := range User().Seed(1).MakeN(500) {
+		if err := validation.Struct(user); err != nil {
+			t.Fatalf("user %+v does not pass validation: %v", user, err)
+		}
+		if phones[user.Phone] {
+			t.Fatalf("phone %s built twice", user.Phone)
+		}
+		phones[user.Phone] = true
+	}
+}
+
+func TestFactorySeedIsReproducible(t *testing.T) {
+	a := User().Seed(42).MakeN(10)
+	b := User().Seed(42).MakeN(10)
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Phone != b[i].Phone || a[i].Role != b[i].Role || a[i].Status != b[i].Status {
+			t.Fatalf("user %d differs with the same seed: %+v, %+v", i, a[i], b[i])
+		}
+	}
+}
+
+func TestFactoryStates(t *testing.T) {
+	base := User().Seed(7)
+	admins := base.With(UserRole(entity.AdminRole))
+	tests := []struct {
+		name    string
+		factory *Factory[userEntity.User]
+		check   func(userEntity.User) bool
+	}{
+		{"role", admins, func(u userEntity.User) bool { return u.Role == entity.AdminRole }},
+		{"states accumulate", admins.With(UserStatus(entity.Inactive)), func(u userEntity.User) bool {
+			return u.Role == entity.AdminRole && u.Status == entity.Inactive
+		}},
+		{"password", base.With(UserPassword("Secret#123")), func(u userEntity.User) bool { return u.Password == "Secret#123" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, user := range tt.factory.MakeN(20) {
+				if !tt.check(user) {
+					t.Fatalf("state not applied to %+v", user)
+				}
+			}
+		})
+	}
+
+	// With returns a new factory, the base one is unchanged
+	if users := base.MakeN(200); allRole(users, entity.AdminRole) {
+		t.Error("With changed the factory it was called on")
+	}
+}
+
+func allRole(users []userEntity.User, role entity.Role) bool {
+	for _, user := range users {
+		if user.Role != role {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCreateNCopiesPreparedRows(t *testing.T) {
+	db := &copyRecorder{}
+	users, err := User().Seed(3).On(db).CreateN(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("CreateN() error = %v", err)
+	}
+
+	if len(db.rows) != 3 || len(users) != 3 {
+		t.Fatalf("copied %d rows and returned %d users, want 3", len(db.rows), len(users))
+	}
+	if db.table.Sanitize() != `"users"` || len(db.columns) != len(db.rows[0]) {
+		t.Fatalf("copied into %s with columns %v", db.table.Sanitize(), db.columns)
+	}
+	for i, row := range db.rows {
+		if row[0] != users[i].Name || row[1] != users[i].Phone {
+			t.Errorf("row %d = %v, want the values of %+v", i, row, users[i])
+		}
+		// Prepare hashed the password before the copy
+		if err := utilQuery.ComparePassword(row[2].(string), DefaultPassword); err != nil {
+			t.Errorf("row %d password is not the hash of the default password: %v", i, err)
+		}
+	}
+}
+
+func TestCreateNWithoutDB(t *testing.T) {
+	if _, err := User().CreateN(context.Background(), 1); err == nil {
+		t.Fatal("CreateN() without On() succeeded")
+	}
+}
+
+func TestFakerPhone(t *testing.T) {
+	pattern := regexp.MustCompile(`^01[3-9][0-9]{8}$`)
+	fake := NewFaker(9)
+	seen := map[string]bool{}
+	for i := 0; i < 10000; i++ {
+		phone := fake.Phone()
+		if !pattern.MatchString(phone) {
+			t.Fatalf("Phone() = %s, not a mobile number", phone)
+		}
+		if seen[phone] {
+			t.Fatalf("Phone() returned %s twice", phone)
+		}
+		seen[phone] = true
+	}
+}
+
+func TestFakerTimeBetween(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		to   time.Time
+	}{
+		{"range", from.Add(48 * time.Hour)},
+		{"empty range", from},
+		{"reversed range", from.Add(-time.Hour)},
+	}
+
+	fake := NewFaker(5)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := fake.TimeBetween(from, tt.to)
+				if !tt.to.After(from) {
+					if !got.Equal(from) {
+						t.Fatalf("TimeBetween() = %s, want %s", got, from)
+					}
+					continue
+				}
+				if got.Before(from) || !got.Before(tt.to) {
+					t.Fatalf("TimeBetween() = %s, not in [%s, %s)", got, from, tt.to)
+				}
+			}
+		})
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	fake := NewFaker(11)
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[Weighted(fake, Choice[string]{"a", 90}, Choice[string]{"b", 10}, Choice[string]{"never", 0})]++
+	}
+	if counts["never"] != 0 {
+		t.Errorf("picked a zero weight choice %d times", counts["never"])
+	}
+	if counts["a"] < 8500 || counts["a"] > 9500 {
+		t.Errorf("picked the 90%% choice %d times out of 10000", counts["a"])
+	}
+}
@@ -0,0 +1,108 @@
+package factory
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+var firstNames = []string{
+	"Abdul", "Abir", "Afsana", "Anika", "Arif", "Ayesha", "Farhana", "Fahim", "Habib", "Imran",
+	"Jannat", "Jubaer", "Kamal", "Karim", "Laila", "Mahmud", "Maliha", "Mehedi", "Mim", "Nabila",
+	"Nadia", "Nasir", "Nusrat", "Rafiq", "Rahim", "Rakib", "Rashed", "Riya", "Sabbir", "Sadia",
+	"Sakib", "Salma", "Shahid", "Shirin", "Sumaiya", "Tahmid", "Tania", "Tanvir", "Yasin", "Zara",
+}
+
+var lastNames = []string{
+	"Ahmed", "Akter", "Alam", "Begum", "Chowdhury", "Das", "Haque", "Hasan", "Hossain", "Islam",
+	"Kabir", "Khan", "Mahmud", "Miah", "Mollah", "Rahman", "Roy", "Sarkar", "Sheikh", "Uddin",
+}
+
+// mobilePrefixes are the operator prefixes of Bangladeshi mobile numbers
+var mobilePrefixes = []string{"013", "014", "015", "016", "017", "018", "019"}
+
+// phoneSpace is the number of distinct phone numbers, 8 digits for each prefix
+const phoneSpace = 7 * 100_000_000
+
+// phoneStride walks the phone space without repeating and scatters consecutive numbers,
+// it is a power of 3 so it shares no factor with phoneSpace
+const phoneStride = 387_420_489
+
+// Faker generates fake data, it is not safe for concurrent use
+type Faker struct {
+	rand     *rand.Rand
+	sequence uint64
+	offset   uint64
+}
+
+// NewFaker creates a faker, a zero seed picks a random one
+func NewFaker(seed uint64) *Faker {
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	random := rand.New(rand.NewPCG(seed, seed>>1))
+	return &Faker{rand: random, offset: random.Uint64N(phoneSpace)}
+}
+
+// IntN returns a number in [0, n)
+func (f *Faker) IntN(n int) int {
+	return f.rand.IntN(n)
+}
+
+// FirstName returns a first name
+func (f *Faker) FirstName() string {
+	return Pick(f, firstNames)
+}
+
+// LastName returns a last name
+func (f *Faker) LastName() string {
+	return Pick(f, lastNames)
+}
+
+// Name returns a full name
+func (f *Faker) Name() string {
+	return f.FirstName() + " " + f.LastName()
+}
+
+// Phone returns an 11 digit mobile number, unique among the numbers of this faker
+func (f *Faker) Phone() string {
+	number := (f.offset + f.sequence*phoneStride) % phoneSpace
+	f.sequence++
+	return fmt.Sprintf("%s%08d", mobilePrefixes[number/100_000_000], number%100_000_000)
+}
+
+// TimeBetween returns a time in [from, to)
+func (f *Faker) TimeBetween(from, to time.Time) time.Time {
+	if !to.After(from) {
+		return from
+	}
+	return from.Add(time.Duration(f.rand.Int64N(int64(to.Sub(from)))))
+}
+
+// Pick returns a random item
+func Pick[T any](f *Faker, items []T) T {
+	return items[f.rand.IntN(len(items))]
+}
+
+// Choice is an item picked proportionally to its weight
+type Choice[T any] struct {
+	Item   T
+	Weight int
+}
+
+// Weighted returns the item of a random choice, picked proportionally to its weight
+func Weighted[T any](f *Faker, choices ...Choice[T]) T {
+	total := 0
+	for _, choice := range choices {
+		total += choice.Weight
+	}
+
+	n := f.rand.IntN(total)
+	for _, choice := range choices {
+		if n < choice.Weight {
+			return choice.Item
+		}
+		n -= choice.Weight
+	}
+	return choices[len(choices)-1].Item
+}
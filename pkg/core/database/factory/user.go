@@ -0,0 +1,91 @@
+package factory
+
+import (
+	"sync"
+	"time"
+
+	userEntity "github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+)
+
+// DefaultPassword is the password of the users built by the user factory, it passes strong_password
+const DefaultPassword = "Password#123"
+
+// passwordHashes caches the bcrypt hash of each password, hashing is by far the slowest part of creating users
+var passwordHashes sync.Map
+
+// HashPassword hashes a password once and returns the same hash afterwards
+func HashPassword(password string) (string, error) {
+	if hash, ok := passwordHashes.Load(password); ok {
+		return hash.(string), nil
+	}
+
+	hash, err := utilQuery.HashPassword(password)
+	if err != nil {
+		return "", err
+	}
+	actual, _ := passwordHashes.LoadOrStore(password, hash)
+	return actual.(string), nil
+}
+
+// User returns a factory of users that pass the validation of the user entity.
+// Passwords are plain text in built users and hashed when they are created.
+func User() *Factory[userEntity.User] {
+	return New(Definition[userEntity.User]{
+		Table:   "users",
+		Columns: []string{"name", "phone", "password", "role", "created_at", "updated_at", "status"},
+		Make: func(fake *Faker) userEntity.User {
+			createdAt := fake.TimeBetween(time.Now().AddDate(-1, 0, 0), time.Now())
+			return userEntity.User{
+				Name:     fake.Name(),
+				Phone:    fake.Phone(),
+				Password: DefaultPassword,
+				Role: Weighted(fake,
+					Choice[entity.Role]{Item: entity.UserRole, Weight: 90},
+					Choice[entity.Role]{Item: entity.ManagerRole, Weight: 8},
+					Choice[entity.Role]{Item: entity.AdminRole, Weight: 2},
+				),
+				CreatedAt: createdAt,
+				UpdatedAt: fake.TimeBetween(createdAt, time.Now()),
+				Status: Weighted(fake,
+					Choice[entity.Status]{Item: entity.Active, Weight: 80},
+					Choice[entity.Status]{Item: entity.Pending, Weight: 15},
+					Choice[entity.Status]{Item: entity.Inactive, Weight: 5},
+				),
+			}
+		},
+		Prepare: func(user *userEntity.User) error {
+			hash, err := HashPassword(user.Password)
+			if err != nil {
+				return err
+			}
+			user.Password = hash
+			return nil
+		},
+		Row: func(user *userEntity.User) []any {
+			return []any{user.Name, user.Phone, user.Password, string(user.Role), user.CreatedAt, user.UpdatedAt, string(user.Status)}
+		},
+	})
+}
+
+// UserRole sets the role of the users
+func UserRole(role entity.Role) func(*userEntity.User) {
+	return func(user *userEntity.User) {
+		user.Role = role
+	}
+}
+
+// UserStatus sets the status of the users
+func UserStatus(status entity.Status) func(*userEntity.User) {
+	return func(user *userEntity.User) {
+		user.Status = status
+	}
+}
+
+// UserPassword sets the plain text password of the users
+func UserPassword(password string) func(*userEntity.User) {
+	return func(user *userEntity.User) {
+		user.Password = password
+	}
+}
@@ -0,0 +1,102 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultLockTimeout is how long to wait for another instance to release the lock
+const DefaultLockTimeout = time.Minute
+
+// lockPollInterval is the delay between two attempts to take the lock
+const lockPollInterval = 500 * time.Millisecond
+
+// LockKey derives the advisory lock key for a name, e.g. "rootx:migrations"
+func LockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
+
+// WithAdvisoryLock runs fn while holding a session-level Postgres advisory lock.
+// It waits up to timeout for the instance holding the lock and logs who it is.
+func WithAdvisoryLock(ctx context.Context, pool *pgxpool.Pool, name string, timeout time.Duration, fn func(waited bool) error) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for lock %s: %w", name, err)
+	}
+	defer conn.Release()
+
+	// Identify this instance to the others waiting on the lock
+	hostname, _ := os.Hostname()
+	if _, err := conn.Exec(ctx, "SELECT set_config('application_name', $1, false)", fmt.Sprintf("%s host=%s pid=%d", name, hostname, os.Getpid())); err != nil {
+		return fmt.Errorf("failed to set application name: %w", err)
+	}
+
+	key := LockKey(name)
+	deadline := time.Now().Add(timeout)
+	waited := false
+	for {
+		var acquired bool
+		if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+			return fmt.Errorf("failed to take lock %s: %w", name, err)
+		}
+		if acquired {
+			break
+		}
+
+		if !waited {
+			log.Printf("waiting for lock %s held by %s", name, lockHolder(ctx, conn, key))
+			waited = true
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for lock %s held by %s", timeout, name, lockHolder(ctx, conn, key))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	defer func() {
+		// Use a fresh context so the lock is released even when ctx was cancelled
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Printf("failed to release lock %s: %v", name, err)
+		}
+		conn.Exec(context.Background(), "RESET application_name")
+	}()
+
+	return fn(waited)
+}
+
+// lockHolder describes the session currently holding the advisory lock
+func lockHolder(ctx context.Context, conn *pgxpool.Conn, key int64) string {
+	var pid int
+	var applicationName, clientAddr string
+	var backendStart time.Time
+	err := conn.QueryRow(ctx, `
+		SELECT a.pid, COALESCE(a.application_name, ''), COALESCE(host(a.client_addr), 'local'), a.backend_start
+		FROM pg_catalog.pg_locks l
+		JOIN pg_catalog.pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted
+			AND l.classid::bigint = $1 AND l.objid::bigint = $2 AND l.objsubid = 1
+		LIMIT 1
+	`, int64(uint64(key)>>32), int64(uint32(key))).Scan(&pid, &applicationName, &clientAddr, &backendStart)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "an instance that has just released it"
+	}
+	if err != nil {
+		return fmt.Sprintf("an unknown session (%v)", err)
+	}
+	return fmt.Sprintf("pid %d (%s, client %s, connected %s)", pid, applicationName, clientAddr, backendStart.Format(time.RFC3339))
+}
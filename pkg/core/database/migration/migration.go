@@ -0,0 +1,528 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LedgerTable is the table that records every applied migration
+const LedgerTable = "schema_migrations"
+
+// LockName identifies the advisory lock serializing migrations across instances
+const LockName = "rootx:migrations"
+
+// DownSuffix marks a file holding the down section of the migration with the same base name
+const DownSuffix = ".down.sql"
+
+// Migration represents a single SQL file in the migrations directory
+type Migration struct {
+	Version  string
+	Name     string
+	Checksum string
+	Up       string
+	Down     string
+}
+
+// RollbackOptions selects which applied migrations are rolled back
+type RollbackOptions struct {
+	// Steps is the number of most recent migrations to roll back
+	Steps int
+	// To rolls back every migration applied after the given applied version or name, "0" rolls back all
+	To string
+}
+
+// AppliedMigration represents a row of the migration ledger
+type AppliedMigration struct {
+	Name      string
+	Version   string
+	Checksum  string
+	AppliedAt time.Time
+	Duration  time.Duration
+}
+
+// Status describes the state of a migration file against the ledger
+type Status struct {
+	Name      string
+	Version   string
+	Applied   bool
+	AppliedAt *time.Time
+	Checksum  string
+	// ChecksumMatch is false when an applied file was edited after it ran
+	ChecksumMatch bool
+	// Missing is true when the ledger records a file that is no longer on disk
+	Missing bool
+}
+
+// State returns a short label for the status: applied, pending, modified or missing
+func (s *Status) State() string {
+	switch {
+	case s.Missing:
+		return "missing"
+	case s.Applied && !s.ChecksumMatch:
+		return "modified"
+	case s.Applied:
+		return "applied"
+	default:
+		return "pending"
+	}
+}
+
+// Migrator applies the SQL files of a source exactly once, in order
+type Migrator struct {
+	pool        *pgxpool.Pool
+	source      MigrationSource
+	lockTimeout time.Duration
+}
+
+// NewMigrator creates a migrator for the files of the given source
+func NewMigrator(pool *pgxpool.Pool, source MigrationSource) *Migrator {
+	return &Migrator{
+		pool:        pool,
+		source:      source,
+		lockTimeout: DefaultLockTimeout,
+	}
+}
+
+// WithLockTimeout sets how long to wait for another instance that is migrating
+func (m *Migrator) WithLockTimeout(timeout time.Duration) *Migrator {
+	if timeout > 0 {
+		m.lockTimeout = timeout
+	}
+	return m
+}
+
+// Up applies all pending migrations and returns how many were applied.
+// Concurrent callers are serialized with an advisory lock, an instance that
+// waited for the lock finds the schema up to date and applies nothing.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := WithAdvisoryLock(ctx, m.pool, LockName, m.lockTimeout, func(waited bool) error {
+		var err error
+		count, err = m.up(ctx)
+		if err == nil && count == 0 && waited {
+			log.Println("schema was brought up to date by another instance")
+		}
+		return err
+	})
+	return count, err
+}
+
+func (m *Migrator) up(ctx context.Context) (int, error) {
+	if err := m.ensureLedger(ctx); err != nil {
+		return 0, err
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Refuse to run anything when an applied file has been edited since
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Name]; ok {
+			continue
+		}
+		if err := m.apply(ctx, migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if count == 0 {
+		log.Println("no pending migrations")
+	} else {
+		log.Printf("%d migration(s) applied successfully", count)
+	}
+	return count, nil
+}
+
+// Rollback executes the down sections of applied migrations in reverse order
+// and returns how many were rolled back
+func (m *Migrator) Rollback(ctx context.Context, opts RollbackOptions) (int, error) {
+	count := 0
+	err := WithAdvisoryLock(ctx, m.pool, LockName, m.lockTimeout, func(bool) error {
+		var err error
+		count, err = m.rollback(ctx, opts)
+		return err
+	})
+	return count, err
+}
+
+func (m *Migrator) rollback(ctx context.Context, opts RollbackOptions) (int, error) {
+	if err := m.ensureLedger(ctx); err != nil {
+		return 0, err
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	targets, err := rollbackTargets(migrations, applied, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range targets {
+		if err := m.revert(ctx, migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if count == 0 {
+		log.Println("nothing to roll back")
+	} else {
+		log.Printf("%d migration(s) rolled back successfully", count)
+	}
+	return count, nil
+}
+
+// Status lists every migration file with its applied or pending state,
+// followed by the ledger entries whose file is missing on disk
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	if err := m.ensureLedger(ctx); err != nil {
+		return nil, err
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []*Status{}
+	onDisk := map[string]bool{}
+	for _, migration := range migrations {
+		onDisk[migration.Name] = true
+		status := &Status{
+			Name:          migration.Name,
+			Version:       migration.Version,
+			Checksum:      migration.Checksum,
+			ChecksumMatch: true,
+		}
+		if record, ok := applied[migration.Name]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.AppliedAt
+			status.ChecksumMatch = record.Checksum == migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	missing := []*Status{}
+	for _, record := range applied {
+		if onDisk[record.Name] {
+			continue
+		}
+		missing = append(missing, &Status{
+			Name:      record.Name,
+			Version:   record.Version,
+			Applied:   true,
+			AppliedAt: &record.AppliedAt,
+			Checksum:  record.Checksum,
+			Missing:   true,
+		})
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Name < missing[j].Name
+	})
+
+	return append(statuses, missing...), nil
+}
+
+// ensureLedger creates the ledger table if it does not exist yet
+func (m *Migrator) ensureLedger(ctx context.Context) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS ` + LedgerTable + ` (
+			name VARCHAR(255) PRIMARY KEY,
+			version VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			duration_ms BIGINT NOT NULL DEFAULT 0
+		)
+	`
+	if _, err := m.pool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", LedgerTable, err)
+	}
+	return nil
+}
+
+// load reads all SQL files of the source sorted by name
+func (m *Migrator) load() ([]*Migration, error) {
+	entries, err := m.source.ReadDir(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", m.source, err)
+	}
+
+	migrations := []*Migration{}
+	downs := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		content, err := m.source.ReadFile(entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s from %s: %w", entry.Name(), m.source, err)
+		}
+
+		if strings.HasSuffix(entry.Name(), DownSuffix) {
+			downs[strings.TrimSuffix(entry.Name(), DownSuffix)+".sql"] = string(content)
+			continue
+		}
+
+		up, down := Parse(string(content))
+		migrations = append(migrations, &Migration{
+			Version:  Version(entry.Name()),
+			Name:     entry.Name(),
+			Checksum: Checksum(content),
+			Up:       up,
+			Down:     down,
+		})
+	}
+
+	for _, migration := range migrations {
+		if down, ok := downs[migration.Name]; ok {
+			if strings.TrimSpace(migration.Down) != "" {
+				return nil, fmt.Errorf("migration %s has both a down section and a %s file", migration.Name, DownSuffix)
+			}
+			migration.Down = down
+		}
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Name < migrations[j].Name
+	})
+	return migrations, nil
+}
+
+// applied returns the ledger rows keyed by file name
+func (m *Migrator) applied(ctx context.Context) (map[string]*AppliedMigration, error) {
+	rows, err := m.pool.Query(ctx, "SELECT name, version, checksum, applied_at, duration_ms FROM "+LedgerTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LedgerTable, err)
+	}
+	defer rows.Close()
+
+	applied := map[string]*AppliedMigration{}
+	for rows.Next() {
+		var migration AppliedMigration
+		var durationMs int64
+		if err := rows.Scan(&migration.Name, &migration.Version, &migration.Checksum, &migration.AppliedAt, &durationMs); err != nil {
+			return nil, err
+		}
+		migration.Duration = time.Duration(durationMs) * time.Millisecond
+		applied[migration.Name] = &migration
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// apply runs a migration and records it in the ledger within one transaction
+func (m *Migrator) apply(ctx context.Context, migration *Migration) error {
+	start := time.Now()
+
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, migration.Up); err != nil {
+		return fmt.Errorf("failed to execute migration %s: %w", migration.Name, err)
+	}
+
+	duration := time.Since(start)
+	query := "INSERT INTO " + LedgerTable + " (name, version, checksum, applied_at, duration_ms) VALUES ($1, $2, $3, $4, $5)"
+	if _, err := tx.Exec(ctx, query, migration.Name, migration.Version, migration.Checksum, time.Now(), duration.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", migration.Name, err)
+	}
+
+	log.Printf("migrated %s (%s)", migration.Name, duration.Round(time.Millisecond))
+	return nil
+}
+
+// revert runs the down section of a migration and removes it from the ledger within one transaction
+func (m *Migrator) revert(ctx context.Context, migration *Migration) error {
+	start := time.Now()
+
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, migration.Down); err != nil {
+		return fmt.Errorf("failed to roll back migration %s: %w", migration.Name, err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM "+LedgerTable+" WHERE name = $1", migration.Name); err != nil {
+		return fmt.Errorf("failed to remove migration %s from ledger: %w", migration.Name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit rollback of %s: %w", migration.Name, err)
+	}
+
+	log.Printf("rolled back %s (%s)", migration.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// rollbackTargets returns the applied migrations to roll back, most recently applied first. The order
+// comes from applied_at in the ledger rather than the file names, so a migration applied out of order
+// is rolled back in the order it actually ran.
+func rollbackTargets(migrations []*Migration, applied map[string]*AppliedMigration, opts RollbackOptions) ([]*Migration, error) {
+	byName := map[string]*Migration{}
+	for _, migration := range migrations {
+		byName[migration.Name] = migration
+	}
+
+	records := []*AppliedMigration{}
+	for _, record := range applied {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].AppliedAt.Equal(records[j].AppliedAt) {
+			return records[i].AppliedAt.After(records[j].AppliedAt)
+		}
+		return records[i].Name > records[j].Name
+	})
+
+	if opts.To != "" {
+		// Keep the migrations applied after the target, which is looked up in the ledger
+		count := len(records)
+		if opts.To != "0" {
+			count = -1
+			for i, record := range records {
+				if record.Version == opts.To || record.Name == opts.To || strings.TrimSuffix(record.Name, ".sql") == opts.To {
+					count = i
+					break
+				}
+			}
+			if count < 0 {
+				return nil, fmt.Errorf("migration %s is not applied", opts.To)
+			}
+		}
+		records = records[:count]
+	} else {
+		if opts.Steps <= 0 {
+			opts.Steps = 1
+		}
+		if opts.Steps < len(records) {
+			records = records[:opts.Steps]
+		}
+	}
+
+	targets := []*Migration{}
+	for _, record := range records {
+		migration, ok := byName[record.Name]
+		if !ok {
+			return nil, fmt.Errorf("cannot roll back %s: file is missing from the migrations directory", record.Name)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("cannot roll back %s: migration has no down section", record.Name)
+		}
+		targets = append(targets, migration)
+	}
+	return targets, nil
+}
+
+// verifyChecksums fails when an applied migration no longer matches its file
+func verifyChecksums(migrations []*Migration, applied map[string]*AppliedMigration) error {
+	for _, migration := range migrations {
+		record, ok := applied[migration.Name]
+		if !ok {
+			continue
+		}
+		if record.Checksum != migration.Checksum {
+			return fmt.Errorf("migration %s was modified after it was applied (checksum %s, ledger %s); create a new migration instead",
+				migration.Name, migration.Checksum[:12], record.Checksum[:12])
+		}
+	}
+	return nil
+}
+
+// Checksum returns the hex encoded SHA-256 of a migration file
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Parse splits a migration file into its "-- +up" and "-- +down" sections.
+// A file without markers is treated as an up-only migration.
+func Parse(content string) (string, string) {
+	var up, down strings.Builder
+	current := &up
+	for _, line := range strings.SplitAfter(content, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +up":
+			current = &up
+			continue
+		case "-- +down":
+			current = &down
+			continue
+		}
+		current.WriteString(line)
+	}
+	return up.String(), down.String()
+}
+
+// Version returns the numeric prefix of a migration file name,
+// e.g. "2024_06_04_193510" for "2024_06_04_193510_roles.sql"
+func Version(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	parts := strings.Split(name, "_")
+
+	version := []string{}
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			break
+		}
+		version = append(version, part)
+	}
+
+	if len(version) == 0 {
+		return name
+	}
+	return strings.Join(version, "_")
+}
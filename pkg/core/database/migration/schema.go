@@ -0,0 +1,245 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SnapshotName is the name of the schema snapshot within the migration source
+const SnapshotName = "schema.json"
+
+// SnapshotFile is the committed snapshot of the schema produced by the migrations
+const SnapshotFile = "migrations/" + SnapshotName
+
+// ignoredTables are bookkeeping tables left out of schema snapshots
+var ignoredTables = map[string]bool{
+	LedgerTable:    true,
+	"schema_seeds": true,
+}
+
+// Schema is a snapshot of the tables, columns and indexes of the public schema
+type Schema struct {
+	Tables map[string]*Table `json:"tables"`
+}
+
+// Table describes the columns and indexes of a table
+type Table struct {
+	Columns map[string]*Column `json:"columns"`
+	Indexes map[string]string  `json:"indexes"`
+}
+
+// Column describes a table column
+type Column struct {
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	Default  string `json:"default,omitempty"`
+}
+
+// String renders the column the way it appears in a drift report
+func (c *Column) String() string {
+	definition := c.Type
+	if !c.Nullable {
+		definition += " NOT NULL"
+	}
+	if c.Default != "" {
+		definition += " DEFAULT " + c.Default
+	}
+	return definition
+}
+
+// InspectSchema reads the live schema from information_schema and pg_catalog
+func InspectSchema(ctx context.Context, pool *pgxpool.Pool) (*Schema, error) {
+	schema := &Schema{Tables: map[string]*Table{}}
+
+	rows, err := pool.Query(ctx, `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tables: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if ignoredTables[name] {
+			continue
+		}
+		schema.Tables[name] = &Table{Columns: map[string]*Column{}, Indexes: map[string]string{}}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = pool.Query(ctx, `
+		SELECT c.relname, a.attname, pg_catalog.format_type(a.atttypid, a.atttypmod),
+			NOT a.attnotnull, COALESCE(pg_catalog.pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_catalog.pg_attribute a
+		JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE n.nspname = 'public' AND c.relkind = 'r' AND a.attnum > 0 AND NOT a.attisdropped
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	for rows.Next() {
+		var tableName, columnName string
+		column := &Column{}
+		if err := rows.Scan(&tableName, &columnName, &column.Type, &column.Nullable, &column.Default); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if table, ok := schema.Tables[tableName]; ok {
+			table.Columns[columnName] = column
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = pool.Query(ctx, "SELECT tablename, indexname, indexdef FROM pg_catalog.pg_indexes WHERE schemaname = 'public'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read indexes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tableName, indexName, definition string
+		if err := rows.Scan(&tableName, &indexName, &definition); err != nil {
+			return nil, err
+		}
+		if table, ok := schema.Tables[tableName]; ok {
+			table.Indexes[indexName] = definition
+		}
+	}
+
+	return schema, rows.Err()
+}
+
+// LoadSnapshot reads a schema snapshot from disk
+func LoadSnapshot(path string) (*Schema, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("schema snapshot %s not found, create it with \"db schema:dump\"", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema snapshot: %w", err)
+	}
+	return parseSnapshot(path, content)
+}
+
+// LoadSourceSnapshot reads the schema snapshot shipped with a migration source
+func LoadSourceSnapshot(source MigrationSource) (*Schema, error) {
+	content, err := source.ReadFile(SnapshotName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("schema snapshot not found in %s, create it with \"db schema:dump\"", source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema snapshot: %w", err)
+	}
+	return parseSnapshot(source.String()+"/"+SnapshotName, content)
+}
+
+// parseSnapshot decodes a schema snapshot, path is only used in errors
+func parseSnapshot(path string, content []byte) (*Schema, error) {
+	schema := &Schema{}
+	if err := json.Unmarshal(content, schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema snapshot %s: %w", path, err)
+	}
+	if schema.Tables == nil {
+		schema.Tables = map[string]*Table{}
+	}
+	return schema, nil
+}
+
+// WriteSnapshot stores a schema snapshot on disk
+func WriteSnapshot(path string, schema *Schema) error {
+	content, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema snapshot: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write schema snapshot: %w", err)
+	}
+	return nil
+}
+
+// Diff reports how the actual schema differs from the expected one.
+// Lines start with "+" for objects only in actual, "-" for objects only
+// in expected and "~" for objects that changed.
+func Diff(expected, actual *Schema) []string {
+	diff := []string{}
+	for _, name := range keys(expected.Tables, actual.Tables) {
+		expectedTable, inExpected := expected.Tables[name]
+		actualTable, inActual := actual.Tables[name]
+		switch {
+		case !inActual:
+			diff = append(diff, "- table "+name)
+			continue
+		case !inExpected:
+			diff = append(diff, "+ table "+name)
+			continue
+		}
+
+		for _, column := range keys(expectedTable.Columns, actualTable.Columns) {
+			want, inExpected := expectedTable.Columns[column]
+			got, inActual := actualTable.Columns[column]
+			switch {
+			case !inActual:
+				diff = append(diff, fmt.Sprintf("- column %s.%s %s", name, column, want))
+			case !inExpected:
+				diff = append(diff, fmt.Sprintf("+ column %s.%s %s", name, column, got))
+			case *want != *got:
+				diff = append(diff, fmt.Sprintf("~ column %s.%s %s -> %s", name, column, want, got))
+			}
+		}
+
+		for _, index := range keys(expectedTable.Indexes, actualTable.Indexes) {
+			want, inExpected := expectedTable.Indexes[index]
+			got, inActual := actualTable.Indexes[index]
+			switch {
+			case !inActual:
+				diff = append(diff, fmt.Sprintf("- index %s: %s", index, want))
+			case !inExpected:
+				diff = append(diff, fmt.Sprintf("+ index %s: %s", index, got))
+			case want != got:
+				diff = append(diff, fmt.Sprintf("~ index %s: %s -> %s", index, want, got))
+			}
+		}
+	}
+	return diff
+}
+
+// keys returns the sorted union of the keys of both maps
+func keys[V any](a, b map[string]V) []string {
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	return sortedKeys(names)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
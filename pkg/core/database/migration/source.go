@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Source kinds accepted by NewSource
+const (
+	// SourceEmbed reads the SQL files compiled into the binary
+	SourceEmbed = "embed"
+	// SourceDisk reads the SQL files from the working directory
+	SourceDisk = "disk"
+)
+
+// MigrationSource provides the SQL files run by the migrator and the seeders
+type MigrationSource interface {
+	// ReadDir lists a directory of the source, "." being its root
+	ReadDir(name string) ([]fs.DirEntry, error)
+	// ReadFile returns the content of a file of the source
+	ReadFile(name string) ([]byte, error)
+	// String describes where the files are read from
+	String() string
+}
+
+// fsSource is a MigrationSource backed by an fs.FS
+type fsSource struct {
+	fsys        fs.FS
+	description string
+}
+
+// NewFSSource returns a source reading the directory dir of fsys
+func NewFSSource(fsys fs.FS, dir string) (MigrationSource, error) {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", dir, err)
+	}
+	return &fsSource{fsys: sub, description: "embedded " + dir}, nil
+}
+
+// NewDirSource returns a source reading the directory dir from disk
+func NewDirSource(dir string) MigrationSource {
+	return &fsSource{fsys: os.DirFS(dir), description: dir}
+}
+
+// NewSource returns the embedded directory dir of fsys, or the same directory on disk when kind is SourceDisk
+func NewSource(kind string, fsys fs.FS, dir string) (MigrationSource, error) {
+	switch kind {
+	case SourceDisk:
+		return NewDirSource(dir), nil
+	case SourceEmbed, "":
+		return NewFSSource(fsys, dir)
+	default:
+		return nil, fmt.Errorf("unknown SQL source %q, expected %q or %q", kind, SourceEmbed, SourceDisk)
+	}
+}
+
+func (s *fsSource) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(s.fsys, name)
+}
+
+func (s *fsSource) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(s.fsys, name)
+}
+
+func (s *fsSource) String() string {
+	return s.description
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
+	"github.com/JubaerHossain/rootx/pkg/core/cache"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNotFound is returned when no row has the given ID
+var ErrNotFound = appError.NewNotFound("not_found", "record not found")
+
+// DB is the connection a repository queries, satisfied by *pgxpool.Pool, *pgx.Conn and pgx.Tx
+type DB = database.Querier
+
+// CRUD is the interface of Repository, domain repositories can embed it
+type CRUD[T any] interface {
+	FindByID(ctx context.Context, id any) (*T, error)
+	ParseList(queryValues url.Values) (utilQuery.ListParams, error)
+	List(ctx context.Context, params utilQuery.ListParams) (*Page[T], error)
+	Create(ctx context.Context, entity *T) error
+	Update(ctx context.Context, id any, fields map[string]any) (*T, error)
+	Delete(ctx context.Context, id any) error
+	Exists(ctx context.Context, where map[string]any) (bool, error)
+}
+
+// Page is a page of entities with its pagination
+type Page[T any] struct {
+	Data       []*T                  `json:"data"`
+	Pagination coreEntity.Pagination `json:"pagination"`
+}
+
+// Hook runs after a write succeeded, e.g. to clear cached lists
+type Hook func(ctx context.Context) error
+
+// Repository gives CRUD on a table to an entity whose fields are mapped to columns by their db tags.
+// Every exported field needs a db tag, db:"-" leaves it out and db:"name,default" lets the database
+// fill the column when the field is zero, e.g. the ID and timestamps. Queries join the transaction of
+// the context when there is one, see database.TxManager.
+type Repository[T any] struct {
+	DB    DB
+	Table string
+	// Key is the primary key column, id by default
+	Key string
+	// Filters are the query parameters List filters on
+	Filters []utilQuery.Field
+	// Sorts are the columns List can sort on with orderBy
+	Sorts []string
+	// DefaultOrder sorts List when orderBy is not given, newest key first by default
+	DefaultOrder string
+	// Hooks run after Create, Update and Delete, or after the commit of their transaction
+	Hooks []Hook
+
+	columns []column
+}
+
+// column is a column mapped to a field of the entity
+type column struct {
+	name  string
+	index []int
+	// dbDefault leaves a zero value to the default of the column on insert
+	dbDefault bool
+}
+
+// New creates the repository of table for the entity T
+func New[T any](db DB, table string) *Repository[T] {
+	var entity T
+	return &Repository[T]{
+		DB:           db,
+		Table:        table,
+		Key:          "id",
+		DefaultOrder: "id DESC",
+		columns:      columnsOf(reflect.TypeOf(entity), nil),
+	}
+}
+
+// columnsOf lists the db tagged fields of a struct and of its embedded structs
+func columnsOf(t reflect.Type, index []int) []column {
+	columns := []column{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		name, options, _ := strings.Cut(field.Tag.Get("db"), ",")
+		// Same rules as pgx.RowToStructByName
+		switch {
+		case field.Anonymous && field.Type.Kind() == reflect.Struct:
+			columns = append(columns, columnsOf(field.Type, fieldIndex)...)
+		case name == "-" || !field.IsExported():
+		case name != "":
+			columns = append(columns, column{name: name, index: fieldIndex, dbDefault: options == "default"})
+		default:
+			panic(fmt.Sprintf("repository: field %s.%s has no db tag", t.Name(), field.Name))
+		}
+	}
+	return columns
+}
+
+// Columns returns the columns of the entity
+func (r *Repository[T]) Columns() []string {
+	names := make([]string, 0, len(r.columns))
+	for _, c := range r.columns {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// conn returns the transaction of the context or DB
+func (r *Repository[T]) conn(ctx context.Context) DB {
+	return database.Conn(ctx, r.DB)
+}
+
+// hasColumn reports whether name is a column of the entity
+func (r *Repository[T]) hasColumn(name string) bool {
+	for _, c := range r.columns {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByID returns the entity with the given ID, ErrNotFound when there is none
+func (r *Repository[T]) FindByID(ctx context.Context, id any) (*T, error) {
+	query := "SELECT " + strings.Join(r.Columns(), ", ") + " FROM " + r.Table + " WHERE " + r.Key + " = $1"
+	rows, err := r.conn(ctx).Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s %v: %w", r.Table, id, appError.FromDB(err))
+	}
+	entity, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[T])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s %v: %w", r.Table, id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s %v: %w", r.Table, id, appError.FromDB(err))
+	}
+	return entity, nil
+}
+
+// ParseList reads a list request from the query string, filtering on Filters and sorting on Sorts
+// with the orderBy and sortBy parameters. Handlers parse the request and pass it to List.
+func (r *Repository[T]) ParseList(queryValues url.Values) (utilQuery.ListParams, error) {
+	return utilQuery.ParseList(queryValues, r.Filters, r.Sorts...)
+}
+
+// List returns a page of entities matching the conditions of params, sorted on one of Sorts
+func (r *Repository[T]) List(ctx context.Context, params utilQuery.ListParams) (*Page[T], error) {
+	page := params.Page
+	if page.Number <= 0 || page.Size <= 0 {
+		page = utilQuery.PageFromQuery(nil)
+	}
+	builder := utilQuery.Select(r.Table, r.Columns()...).
+		Conditions(params.Conditions...).
+		Sort(params.Sort, r.DefaultOrder, r.Sorts...).
+		ThenBy(r.Key).
+		Page(page)
+
+	countQuery, countArgs, err := builder.CountSQL()
+	if err != nil {
+		return nil, err
+	}
+	var totalItems int
+	if err := r.conn(ctx).QueryRow(ctx, countQuery, countArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count %s: %w", r.Table, appError.FromDB(err))
+	}
+
+	result := &Page[T]{Data: []*T{}, Pagination: utilQuery.NewPagination(page, totalItems)}
+	if page.Offset() >= totalItems {
+		return result, nil
+	}
+
+	query, args, err := builder.SQL()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.conn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.Table, appError.FromDB(err))
+	}
+	if result.Data, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[T]); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", r.Table, appError.FromDB(err))
+	}
+	return result, nil
+}
+
+// Create inserts every column of the entity, zero values included, and reads the row back into it.
+// Zero fields tagged db:"name,default" are left out so the database fills them.
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	value := reflect.ValueOf(entity).Elem()
+	names := []string{}
+	placeholders := []string{}
+	args := []any{}
+	for _, c := range r.columns {
+		field := value.FieldByIndex(c.index)
+		if c.dbDefault && field.IsZero() {
+			continue
+		}
+		names = append(names, c.name)
+		args = append(args, field.Interface())
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+
+	query := "INSERT INTO " + r.Table + " (" + strings.Join(names, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+	if len(names) == 0 {
+		query = "INSERT INTO " + r.Table + " DEFAULT VALUES"
+	}
+	rows, err := r.conn(ctx).Query(ctx, query+" RETURNING "+strings.Join(r.Columns(), ", "), args...)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", r.Table, appError.FromDB(err))
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[T])
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", r.Table, appError.FromDB(err))
+	}
+	*entity = created
+
+	return r.afterWrite(ctx)
+}
+
+// Update sets the given columns of the entity with the given ID and returns it, updated_at is
+// refreshed when the table has one. It returns ErrNotFound when there is no such entity.
+func (r *Repository[T]) Update(ctx context.Context, id any, fields map[string]any) (*T, error) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if !r.hasColumn(name) || name == r.Key {
+			return nil, fmt.Errorf("cannot update column %q of %s", name, r.Table)
+		}
+		names = append(names, name)
+	}
+	// Sorted so the same fields always produce the same statement
+	sort.Strings(names)
+
+	assignments := []string{}
+	args := []any{}
+	for _, name := range names {
+		args = append(args, fields[name])
+		assignments = append(assignments, name+" = $"+strconv.Itoa(len(args)))
+	}
+	if _, ok := fields["updated_at"]; !ok && r.hasColumn("updated_at") {
+		assignments = append(assignments, "updated_at = CURRENT_TIMESTAMP")
+	}
+	if len(assignments) == 0 {
+		return r.FindByID(ctx, id)
+	}
+
+	args = append(args, id)
+	query := "UPDATE " + r.Table + " SET " + strings.Join(assignments, ", ") +
+		" WHERE " + r.Key + " = $" + strconv.Itoa(len(args)) + " RETURNING " + strings.Join(r.Columns(), ", ")
+	rows, err := r.conn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s %v: %w", r.Table, id, appError.FromDB(err))
+	}
+	entity, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[T])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s %v: %w", r.Table, id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s %v: %w", r.Table, id, appError.FromDB(err))
+	}
+
+	return entity, r.afterWrite(ctx)
+}
+
+// Delete deletes the entity with the given ID, ErrNotFound when there is none
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	tag, err := r.conn(ctx).Exec(ctx, "DELETE FROM "+r.Table+" WHERE "+r.Key+" = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s %v: %w", r.Table, id, appError.FromDB(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s %v: %w", r.Table, id, ErrNotFound)
+	}
+	return r.afterWrite(ctx)
+}
+
+// Exists reports whether a row has all the given column values
+func (r *Repository[T]) Exists(ctx context.Context, where map[string]any) (bool, error) {
+	names := make([]string, 0, len(where))
+	for name := range where {
+		if !r.hasColumn(name) {
+			return false, fmt.Errorf("unknown column %q of %s", name, r.Table)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filter := &utilQuery.Filter{}
+	for _, name := range names {
+		filter.Add(name+" = ?", where[name])
+	}
+
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM " + r.Table + filter.Where() + ")"
+	if err := r.conn(ctx).QueryRow(ctx, query, filter.Args()...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", r.Table, appError.FromDB(err))
+	}
+	return exists, nil
+}
+
+// afterWrite runs the hooks, once the transaction of the context commits when there is one
+func (r *Repository[T]) afterWrite(ctx context.Context) error {
+	for _, hook := range r.Hooks {
+		if err := database.AfterCommit(ctx, hook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateCache returns a hook clearing the cache keys matching the patterns, e.g. "get_all_users_*"
+func InvalidateCache(cacheService cache.CacheService, patterns ...string) Hook {
+	return func(ctx context.Context) error {
+		for _, pattern := range patterns {
+			if _, err := cacheService.ClearPattern(ctx, pattern); err != nil {
+				return fmt.Errorf("failed to clear cache %s: %w", pattern, err)
+			}
+		}
+		return nil
+	}
+}
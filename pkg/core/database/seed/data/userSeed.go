@@ -6,70 +6,123 @@ import (
 	"time"
 
 	userEntity "github.com/JubaerHossain/rootx/domain/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/database/factory"
+	"github.com/JubaerHossain/rootx/pkg/core/database/seed"
 	"github.com/JubaerHossain/rootx/pkg/core/entity"
 	"github.com/JubaerHossain/rootx/pkg/core/logger"
-	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
-var roles = []entity.Role{entity.AdminRole, entity.ManagerRole, entity.UserRole}
+var roles = entity.Roles
 
-// SeedUsers generates and inserts dummy user data into the database.
-func SeedUsers(pool *pgxpool.Pool, numUsers int) error {
-	// Begin transaction
-	tx, err := pool.Begin(context.Background())
-	if err != nil {
-		return err
-	}
+func init() {
+	seed.Register(seed.Seeder{
+		Name: "roles",
+		Run:  SeedRoles,
+	})
+	seed.Register(seed.Seeder{
+		Name:      "users",
+		DependsOn: []string{"roles"},
+		Envs:      []string{seed.EnvDev, seed.EnvTest},
+		Run:       SeedUsers,
+	})
+}
 
-	// Defer a function to handle transaction rollback or commit
-	defer func() {
-		if r := recover(); r != nil {
-			// Recover from panic and rollback the transaction
-			tx.Rollback(context.Background())
-		} else {
-			// Commit the transaction if no error occurred
-			if err := tx.Commit(context.Background()); err != nil {
-				logger.Error("Error committing transaction:", zap.Error(err))
-			}
+// SeedRoles inserts the roles users can have, skipping those that already exist
+func SeedRoles(ctx context.Context, tx pgx.Tx, params seed.Params) error {
+	query := `
+		INSERT INTO roles (name, description, created_at, updated_at)
+		SELECT $1::VARCHAR, $2, $3, $3
+		WHERE NOT EXISTS (SELECT 1 FROM roles WHERE name = $1)
+	`
+	for _, role := range roles {
+		if _, err := tx.Exec(ctx, query, string(role), fmt.Sprintf("Users with the %s role", role), time.Now()); err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", role, err)
 		}
-	}()
-
-	// Delete existing users and update logs
-	_, err = tx.Exec(context.Background(), "DELETE FROM users")
-	if err != nil {
-		return err
 	}
 
-	// Hash the default password
-	password, err := utilQuery.HashPassword("password")
-	if err != nil {
-		return err
-	}
+	logger.Info("Seeded roles successfully", zap.Int("count", len(roles)))
+	return nil
+}
+
+// SeedUsers inserts one user per role with a well known phone number, 0170000000<n>, so the demo can log in,
+// then params.Count users with fake data. All of them have the password factory.DefaultPassword.
+func SeedUsers(ctx context.Context, tx pgx.Tx, params seed.Params) error {
+	users := factory.User().With(factory.UserStatus(entity.Active))
 
-	// Loop through roles and create users
+	query := `
+		INSERT INTO users (name, phone, password, role, created_at, updated_at, status) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (phone) DO NOTHING
+	`
 	for i, role := range roles {
-		var user userEntity.User
-		user.Name = string(role)
+		user := users.With(factory.UserRole(role)).Make()
 		user.Phone = fmt.Sprintf("0170000000%d", i+1)
-		user.Password = password
-		user.Role = role
-		user.CreatedAt = time.Now()
-		user.UpdatedAt = time.Now()
-		user.Status = entity.Active
-
-		fmt.Println(user)
-		// Construct the insert query with placeholders
-		query := `INSERT INTO users (name, phone, password, role, created_at, updated_at, status) VALUES ($1, $2, $3, $4, $5, $6, $7)`
-
-		// Execute the query with user data as arguments
-		_, err := tx.Exec(context.Background(), query, user.Name, user.Phone, user.Password, user.Role, user.CreatedAt, user.UpdatedAt, user.Status)
+		password, err := factory.HashPassword(user.Password)
 		if err != nil {
 			return err
 		}
+
+		if _, err := tx.Exec(ctx, query, user.Name, user.Phone, password, user.Role, user.CreatedAt, user.UpdatedAt, user.Status); err != nil {
+			return fmt.Errorf("failed to seed %s user: %w", role, err)
+		}
 	}
 
-	logger.Info("Seeded users successfully")
+	// The phones are only unique within one faker, those taken by earlier seeds are replaced before the COPY
+	created := users.MakeN(params.CountOr(0))
+	if err := replaceTakenPhones(ctx, tx, users, created); err != nil {
+		return err
+	}
+	if err := users.On(tx).Insert(ctx, created); err != nil {
+		return err
+	}
+
+	logger.Info("Seeded users successfully", zap.Int("count", len(roles)+len(created)))
+	return nil
+}
+
+// phoneBatch is the number of phones looked up at once
+const phoneBatch = 10_000
+
+// replaceTakenPhones gives the users whose phone is already in the users table a new phone from the factory,
+// which never repeats its own phones, until none is taken
+func replaceTakenPhones(ctx context.Context, tx pgx.Tx, users *factory.Factory[userEntity.User], created []userEntity.User) error {
+	// pending are the indexes of the users whose phone is not checked yet
+	pending := make([]int, len(created))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for len(pending) > 0 {
+		replaced := []int{}
+		for start := 0; start < len(pending); start += phoneBatch {
+			batch := pending[start:min(start+phoneBatch, len(pending))]
+			phones := make([]string, len(batch))
+			for i, index := range batch {
+				phones[i] = created[index].Phone
+			}
+
+			rows, err := tx.Query(ctx, "SELECT phone FROM users WHERE phone = ANY($1)", phones)
+			if err != nil {
+				return fmt.Errorf("failed to look up seeded phones: %w", err)
+			}
+			existing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+			if err != nil {
+				return fmt.Errorf("failed to look up seeded phones: %w", err)
+			}
+
+			taken := map[string]bool{}
+			for _, phone := range existing {
+				taken[phone] = true
+			}
+			for _, index := range batch {
+				if taken[created[index].Phone] {
+					created[index].Phone = users.Make().Phone
+					replaced = append(replaced, index)
+				}
+			}
+		}
+		pending = replaced
+	}
 	return nil
 }
@@ -0,0 +1,397 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/database/migration"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LedgerTable is the table that records every seeder that has run
+const LedgerTable = "schema_seeds"
+
+// LockName identifies the advisory lock serializing seeders across instances
+const LockName = "rootx:seeds"
+
+// Environment directories holding the seeds that only run in one environment,
+// seeds at the root of the seeds directory run everywhere
+const (
+	EnvDev  = "dev"
+	EnvTest = "test"
+	EnvProd = "prod"
+)
+
+// EnvDir maps APP_ENV to the directory of its environment scoped seeds
+func EnvDir(appEnv string) string {
+	switch strings.ToLower(strings.TrimSpace(appEnv)) {
+	case "prod", "production":
+		return EnvProd
+	case "test", "testing":
+		return EnvTest
+	default:
+		return EnvDev
+	}
+}
+
+// RunOptions selects which seeders run
+type RunOptions struct {
+	// Force runs seeders again even when the ledger says they already ran
+	Force bool
+	// Only runs a single seeder and the seeders it depends on, e.g. "users",
+	// "seed_users.sql" or "dev/seed_users.sql"
+	Only string
+	// Params are passed to the Go seeders
+	Params Params
+}
+
+// DependsDirective declares the seeders a SQL seed depends on, e.g. "-- +depends: roles, users"
+const DependsDirective = "-- +depends:"
+
+// SQLSeed is a SQL file of the seeds directory
+type SQLSeed struct {
+	Name      string
+	Checksum  string
+	SQL       string
+	DependsOn []string
+}
+
+// seedJob is a SQL or Go seeder scheduled by the runner
+type seedJob struct {
+	name      string
+	checksum  string
+	dependsOn []string
+	sql       *SQLSeed
+	seeder    *Seeder
+}
+
+// sortKey orders jobs that do not depend on each other, SQL seeds by file name
+// so the environment directory does not matter, Go seeders by name
+func (j *seedJob) sortKey() string {
+	return path.Base(j.name)
+}
+
+// Runner runs the SQL seeds of a source and the registered Go seeders once,
+// recording them in the seed ledger
+type Runner struct {
+	pool        *pgxpool.Pool
+	source      migration.MigrationSource
+	env         string
+	lockTimeout time.Duration
+}
+
+// NewRunner creates a runner for the shared seeds and the seeds of the given APP_ENV
+func NewRunner(pool *pgxpool.Pool, source migration.MigrationSource, appEnv string) *Runner {
+	return &Runner{
+		pool:        pool,
+		source:      source,
+		env:         EnvDir(appEnv),
+		lockTimeout: migration.DefaultLockTimeout,
+	}
+}
+
+// WithLockTimeout sets how long to wait for another instance that is seeding
+func (r *Runner) WithLockTimeout(timeout time.Duration) *Runner {
+	if timeout > 0 {
+		r.lockTimeout = timeout
+	}
+	return r
+}
+
+// Run executes the seeders that have not run yet and returns how many ran
+func (r *Runner) Run(ctx context.Context, opts RunOptions) (int, error) {
+	count := 0
+	err := migration.WithAdvisoryLock(ctx, r.pool, LockName, r.lockTimeout, func(bool) error {
+		var err error
+		count, err = r.run(ctx, opts)
+		return err
+	})
+	return count, err
+}
+
+func (r *Runner) run(ctx context.Context, opts RunOptions) (int, error) {
+	if err := r.ensureLedger(ctx); err != nil {
+		return 0, err
+	}
+
+	jobs, err := r.plan(opts.Only)
+	if err != nil {
+		return 0, err
+	}
+
+	ledger, err := r.ledger(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, job := range jobs {
+		if checksum, ok := ledger[job.name]; ok && !opts.Force {
+			if checksum != job.checksum {
+				log.Printf("seeder %s changed since it ran, use force to run it again", job.name)
+			} else if job.name == opts.Only || job.sortKey() == opts.Only {
+				log.Printf("seeder %s already ran, use force to run it again", job.name)
+			}
+			continue
+		}
+
+		if err := r.apply(ctx, job, opts.Params); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if count == 0 {
+		log.Println("no pending seeders")
+	} else {
+		log.Printf("%d seeder(s) executed successfully", count)
+	}
+	return count, nil
+}
+
+// plan returns the SQL and Go seeders of the environment in dependency order.
+// Seeders that do not depend on each other keep the file name order. When only
+// is set, the plan is limited to that seeder and the seeders it depends on.
+func (r *Runner) plan(only string) ([]*seedJob, error) {
+	seeds, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*seedJob{}
+	for _, seed := range seeds {
+		jobs = append(jobs, &seedJob{name: seed.Name, checksum: seed.Checksum, dependsOn: seed.DependsOn, sql: seed})
+	}
+	for _, seeder := range Registered() {
+		if !seeder.runsIn(r.env) {
+			continue
+		}
+		// Go seeders have no content to hash, the checksum only tells them apart from SQL seeds
+		jobs = append(jobs, &seedJob{name: seeder.Name, checksum: migration.Checksum([]byte("go:" + seeder.Name)), dependsOn: seeder.DependsOn, seeder: seeder})
+	}
+
+	// A seeder can be referred to by its name, its file name or its file name without extension
+	byName := map[string]*seedJob{}
+	for _, job := range jobs {
+		for _, alias := range []string{job.name, job.sortKey(), strings.TrimSuffix(job.sortKey(), ".sql")} {
+			if other, exists := byName[alias]; exists && other != job {
+				return nil, fmt.Errorf("seeders %s and %s are both named %s", other.name, job.name, alias)
+			}
+			byName[alias] = job
+		}
+	}
+
+	dependencies := map[*seedJob][]*seedJob{}
+	for _, job := range jobs {
+		for _, name := range job.dependsOn {
+			dependency, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("seeder %s depends on %s which is not found in %s or the Go seeders of the %s environment", job.name, name, r.source, r.env)
+			}
+			dependencies[job] = append(dependencies[job], dependency)
+		}
+	}
+
+	if only != "" {
+		target, ok := byName[only]
+		if !ok {
+			return nil, fmt.Errorf("seeder %s not found in %s or the Go seeders of the %s environment", only, r.source, r.env)
+		}
+
+		selected := map[*seedJob]bool{}
+		var visit func(job *seedJob)
+		visit = func(job *seedJob) {
+			if selected[job] {
+				return
+			}
+			selected[job] = true
+			for _, dependency := range dependencies[job] {
+				visit(dependency)
+			}
+		}
+		visit(target)
+
+		filtered := []*seedJob{}
+		for _, job := range jobs {
+			if selected[job] {
+				filtered = append(filtered, job)
+			}
+		}
+		jobs = filtered
+	}
+
+	return sortJobs(jobs, dependencies)
+}
+
+// sortJobs orders the jobs so every job comes after its dependencies, picking
+// the lowest sort key among the jobs that are ready to keep the order stable
+func sortJobs(jobs []*seedJob, dependencies map[*seedJob][]*seedJob) ([]*seedJob, error) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].sortKey() < jobs[j].sortKey()
+	})
+
+	done := map[*seedJob]bool{}
+	sorted := make([]*seedJob, 0, len(jobs))
+	for len(sorted) < len(jobs) {
+		progressed := false
+		for _, job := range jobs {
+			if done[job] || !ready(job, dependencies, done) {
+				continue
+			}
+			done[job] = true
+			sorted = append(sorted, job)
+			progressed = true
+			break
+		}
+
+		if !progressed {
+			blocked := []string{}
+			for _, job := range jobs {
+				if !done[job] {
+					blocked = append(blocked, job.name)
+				}
+			}
+			return nil, fmt.Errorf("seeders have a dependency cycle: %s", strings.Join(blocked, ", "))
+		}
+	}
+	return sorted, nil
+}
+
+// ready reports whether all dependencies of the job are done
+func ready(job *seedJob, dependencies map[*seedJob][]*seedJob, done map[*seedJob]bool) bool {
+	for _, dependency := range dependencies[job] {
+		if !done[dependency] {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureLedger creates the ledger table if it does not exist yet
+func (r *Runner) ensureLedger(ctx context.Context) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS ` + LedgerTable + ` (
+			name VARCHAR(255) PRIMARY KEY,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			duration_ms BIGINT NOT NULL DEFAULT 0
+		)
+	`
+	if _, err := r.pool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", LedgerTable, err)
+	}
+	return nil
+}
+
+// load reads the shared seeds and the seeds of the environment
+func (r *Runner) load() ([]*SQLSeed, error) {
+	seeds := []*SQLSeed{}
+	for _, dir := range []string{".", r.env} {
+		entries, err := r.source.ReadDir(dir)
+		if err != nil {
+			if dir != "." {
+				// The environment has no scoped seeds
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", r.source, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+				continue
+			}
+
+			name := path.Join(dir, entry.Name())
+			content, err := r.source.ReadFile(name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", name, err)
+			}
+
+			seeds = append(seeds, &SQLSeed{
+				Name:      name,
+				Checksum:  migration.Checksum(content),
+				SQL:       string(content),
+				DependsOn: parseDepends(string(content)),
+			})
+		}
+	}
+
+	return seeds, nil
+}
+
+// parseDepends reads the seeders listed by the depends directives of a SQL seed
+func parseDepends(content string) []string {
+	dependsOn := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, DependsDirective) {
+			continue
+		}
+		for _, name := range strings.Split(strings.TrimPrefix(line, DependsDirective), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				dependsOn = append(dependsOn, name)
+			}
+		}
+	}
+	return dependsOn
+}
+
+// ledger returns the checksum of every seeder that has run, keyed by name
+func (r *Runner) ledger(ctx context.Context) (map[string]string, error) {
+	rows, err := r.pool.Query(ctx, "SELECT name, checksum FROM "+LedgerTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LedgerTable, err)
+	}
+	defer rows.Close()
+
+	ledger := map[string]string{}
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			return nil, err
+		}
+		ledger[name] = checksum
+	}
+	return ledger, rows.Err()
+}
+
+// apply runs a seeder and records it in the ledger, committing or rolling back both together
+func (r *Runner) apply(ctx context.Context, job *seedJob, params Params) error {
+	start := time.Now()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if job.seeder != nil {
+		err = job.seeder.Run(ctx, tx, params)
+	} else {
+		_, err = tx.Exec(ctx, job.sql.SQL)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to execute seeder %s: %w", job.name, err)
+	}
+
+	duration := time.Since(start)
+	query := `
+		INSERT INTO ` + LedgerTable + ` (name, checksum, applied_at, duration_ms) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET checksum = EXCLUDED.checksum, applied_at = EXCLUDED.applied_at, duration_ms = EXCLUDED.duration_ms
+	`
+	if _, err := tx.Exec(ctx, query, job.name, job.checksum, time.Now(), duration.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to record seeder %s: %w", job.name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit seeder %s: %w", job.name, err)
+	}
+
+	log.Printf("seeded %s (%s)", job.name, duration.Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,166 @@
+package seed
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JubaerHossain/rootx/pkg/core/database/migration"
+	"github.com/jackc/pgx/v5"
+)
+
+// withSeeders replaces the Go seeder registry for the duration of a test
+func withSeeders(t *testing.T, seeders ...Seeder) {
+	t.Helper()
+	saved := registry
+	registry = map[string]*Seeder{}
+	t.Cleanup(func() { registry = saved })
+	for _, seeder := range seeders {
+		seeder.Run = func(context.Context, pgx.Tx, Params) error { return nil }
+		Register(seeder)
+	}
+}
+
+// source returns a seeds directory holding the files
+func source(t *testing.T, files map[string]string) migration.MigrationSource {
+	t.Helper()
+	fsys := fstest.MapFS{"seeds/.keep": {}}
+	for name, content := range files {
+		fsys["seeds/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	src, err := migration.NewFSSource(fsys, "seeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		seeders []Seeder
+		env     string
+		only    string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "file name order without dependencies",
+			files: map[string]string{"b.sql": "", "a.sql": "", "c.sql": ""},
+			want:  []string{"a.sql", "b.sql", "c.sql"},
+		},
+		{
+			name:  "dependency runs first",
+			files: map[string]string{"a.sql": "-- +depends: c\n", "b.sql": "", "c.sql": ""},
+			want:  []string{"b.sql", "c.sql", "a.sql"},
+		},
+		{
+			name:    "go and sql seeders depend on each other",
+			files:   map[string]string{"1_roles.sql": "", "3_bookings.sql": "-- +depends: users\n"},
+			seeders: []Seeder{{Name: "users", DependsOn: []string{"1_roles"}}},
+			want:    []string{"1_roles.sql", "users", "3_bookings.sql"},
+		},
+		{
+			name:    "environment seeds are sorted by file name with the shared ones",
+			files:   map[string]string{"1_a.sql": "", "dev/2_b.sql": "", "prod/0_c.sql": "", "3_d.sql": ""},
+			seeders: []Seeder{{Name: "0_prod_only", Envs: []string{EnvProd}}, {Name: "4_dev_only", Envs: []string{EnvDev}}},
+			env:     "development",
+			want:    []string{"1_a.sql", "dev/2_b.sql", "3_d.sql", "4_dev_only"},
+		},
+		{
+			name:  "only keeps the seeder and its dependencies",
+			files: map[string]string{"a.sql": "", "b.sql": "-- +depends: a\n", "c.sql": "-- +depends: b\n", "d.sql": ""},
+			only:  "b",
+			want:  []string{"a.sql", "b.sql"},
+		},
+		{
+			name:    "cycle",
+			files:   map[string]string{"a.sql": "-- +depends: b\n", "b.sql": "-- +depends: a\n", "c.sql": ""},
+			wantErr: "dependency cycle: a.sql, b.sql",
+		},
+		{
+			name:    "missing dependency",
+			files:   map[string]string{"a.sql": "-- +depends: missing\n"},
+			wantErr: "depends on missing which is not found",
+		},
+		{
+			name:    "dependency of another environment",
+			files:   map[string]string{"a.sql": "-- +depends: b\n", "prod/b.sql": ""},
+			env:     "dev",
+			wantErr: "depends on b which is not found",
+		},
+		{
+			name:    "unknown only",
+			files:   map[string]string{"a.sql": ""},
+			only:    "b",
+			wantErr: "seeder b not found",
+		},
+		{
+			name:    "duplicate name",
+			files:   map[string]string{"users.sql": ""},
+			seeders: []Seeder{{Name: "users"}},
+			wantErr: "are both named users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withSeeders(t, tt.seeders...)
+			runner := NewRunner(nil, source(t, tt.files), tt.env)
+
+			jobs, err := runner.plan(tt.only)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("plan() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("plan() error = %v", err)
+			}
+
+			got := []string{}
+			for _, job := range jobs {
+				got = append(got, job.name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("plan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDepends(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{content: "INSERT INTO roles VALUES (1);", want: []string{}},
+		{content: "-- +depends: roles\nINSERT", want: []string{"roles"}},
+		{content: "  -- +depends: roles, users ,\n-- +depends: dev/seed_a.sql", want: []string{"roles", "users", "dev/seed_a.sql"}},
+	}
+
+	for _, tt := range tests {
+		got := parseDepends(tt.content)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("parseDepends(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestEnvDir(t *testing.T) {
+	tests := map[string]string{
+		"":            EnvDev,
+		"development": EnvDev,
+		"Production":  EnvProd,
+		" prod ":      EnvProd,
+		"testing":     EnvTest,
+	}
+	for appEnv, want := range tests {
+		if got := EnvDir(appEnv); got != want {
+			t.Errorf("EnvDir(%q) = %q, want %q", appEnv, got, want)
+		}
+	}
+}
@@ -2,34 +2,73 @@ package seed
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"sort"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 )
 
-// NewSeed seeds the database
-func NewSeed(pool *pgxpool.Pool) error {
-	ctx := context.Background()
+// Params are the parameters given to Go seeders, e.g. from "db seed --count 500"
+type Params struct {
+	// Count is the number of rows to create, seeders pick their own default when zero
+	Count int
+}
+
+// CountOr returns Count, or fallback when no count was given
+func (p Params) CountOr(fallback int) int {
+	if p.Count > 0 {
+		return p.Count
+	}
+	return fallback
+}
+
+// Seeder is a seeder written in Go
+type Seeder struct {
+	// Name identifies the seeder in the ledger and in dependencies
+	Name string
+	// DependsOn lists the Go or SQL seeders that must run first
+	DependsOn []string
+	// Envs restricts the seeder to some environments (dev, test, prod), it runs everywhere when empty
+	Envs []string
+	// Run seeds the database within the transaction of the seeder
+	Run func(ctx context.Context, tx pgx.Tx, params Params) error
+}
 
-	// Example of seeding logic
-	conn, err := pool.Acquire(ctx)
-	if err != nil {
-		return err
+// registry holds the Go seeders by name
+var registry = map[string]*Seeder{}
+
+// Register adds a Go seeder, usually from the init function of the package defining it
+func Register(seeder Seeder) {
+	if seeder.Name == "" || seeder.Run == nil {
+		panic("seed: seeder needs a name and a run function")
+	}
+	if _, exists := registry[seeder.Name]; exists {
+		panic(fmt.Sprintf("seed: seeder %s registered twice", seeder.Name))
 	}
-	defer conn.Release()
+	registry[seeder.Name] = &seeder
+}
 
-	// Assuming you have some SQL statements for seeding
-	seedStatements := []string{
-		`INSERT INTO users (id, name) VALUES (1, 'John Doe');`,
-		// Add more seed statements here
+// Registered returns the Go seeders sorted by name
+func Registered() []*Seeder {
+	seeders := make([]*Seeder, 0, len(registry))
+	for _, seeder := range registry {
+		seeders = append(seeders, seeder)
 	}
+	sort.Slice(seeders, func(i, j int) bool {
+		return seeders[i].Name < seeders[j].Name
+	})
+	return seeders
+}
 
-	for _, stmt := range seedStatements {
-		if _, err := conn.Exec(ctx, stmt); err != nil {
-			return err
+// runsIn reports whether the seeder runs in the given environment directory
+func (s *Seeder) runsIn(env string) bool {
+	if len(s.Envs) == 0 {
+		return true
+	}
+	for _, allowed := range s.Envs {
+		if allowed == env {
+			return true
 		}
 	}
-
-	log.Println("database seeding completed")
-	return nil
+	return false
 }
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultTxRetries is how many times a transaction is retried after a serialization failure or a deadlock
+const DefaultTxRetries = 3
+
+// Querier runs queries, satisfied by *pgxpool.Pool, *pgx.Conn and pgx.Tx
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Beginner starts transactions, satisfied by *pgxpool.Pool and *pgx.Conn
+type Beginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// txKey is the context key of the ambient transaction
+type txKey struct{}
+
+// ambientTx is the transaction stored in the context with the callbacks to run once it commits
+type ambientTx struct {
+	tx          pgx.Tx
+	afterCommit []func(ctx context.Context) error
+}
+
+// Conn returns the transaction of the context, or db when there is none.
+// Repositories query through it so they join the transaction of WithinTx.
+func Conn(ctx context.Context, db Querier) Querier {
+	if ambient, ok := ctx.Value(txKey{}).(*ambientTx); ok {
+		return ambient.tx
+	}
+	return db
+}
+
+// TxFromContext returns the transaction of the context
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	ambient, ok := ctx.Value(txKey{}).(*ambientTx)
+	if !ok {
+		return nil, false
+	}
+	return ambient.tx, true
+}
+
+// AfterCommit runs fn once the transaction of the context commits, e.g. to clear a cache,
+// or right away when there is no transaction. It is dropped when the transaction rolls back.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ambient, ok := ctx.Value(txKey{}).(*ambientTx); ok {
+		ambient.afterCommit = append(ambient.afterCommit, fn)
+		return nil
+	}
+	return fn(ctx)
+}
+
+// TxOption configures a transaction of WithinTx
+type TxOption func(*txConfig)
+
+type txConfig struct {
+	options pgx.TxOptions
+	retries int
+}
+
+// WithIsolation sets the isolation level, e.g. pgx.Serializable
+func WithIsolation(level pgx.TxIsoLevel) TxOption {
+	return func(c *txConfig) { c.options.IsoLevel = level }
+}
+
+// WithReadOnly starts a read only transaction
+func WithReadOnly() TxOption {
+	return func(c *txConfig) { c.options.AccessMode = pgx.ReadOnly }
+}
+
+// WithRetries sets how many times the transaction is retried, 0 disables retries
+func WithRetries(retries int) TxOption {
+	return func(c *txConfig) { c.retries = retries }
+}
+
+// TxManager runs functions in a transaction carried by their context
+type TxManager struct {
+	db Beginner
+}
+
+// NewTxManager creates a transaction manager on a pool or connection
+func NewTxManager(db Beginner) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithinTx runs fn in a transaction that is committed when fn returns nil and rolled back
+// when it returns an error or panics. The context given to fn carries the transaction, so
+// repositories called with it take part in it and a nested WithinTx joins it. The whole
+// transaction is retried when it fails on a serialization failure or a deadlock.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	// A nested call joins the outer transaction, which commits, rolls back and retries
+	if _, ok := ctx.Value(txKey{}).(*ambientTx); ok {
+		return fn(ctx)
+	}
+
+	config := txConfig{retries: DefaultTxRetries}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	for attempt := 0; ; attempt++ {
+		ambient, err := m.run(ctx, config.options, fn)
+		if err == nil {
+			for _, callback := range ambient.afterCommit {
+				if err := callback(ctx); err != nil {
+					return fmt.Errorf("transaction committed but a callback failed: %w", err)
+				}
+			}
+			return nil
+		}
+		if !retryable(err) || attempt >= config.retries {
+			return err
+		}
+
+		// Back off a little, with jitter, before the next attempt
+		backoff := time.Duration(10<<attempt)*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// run makes one attempt, ending the transaction with exactly one commit or rollback
+func (m *TxManager) run(ctx context.Context, options pgx.TxOptions, fn func(ctx context.Context) error) (ambient *ambientTx, err error) {
+	tx, err := m.db.BeginTx(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	ambient = &ambientTx{tx: tx}
+
+	defer func() {
+		if p := recover(); p != nil {
+			// The context may be canceled, the rollback must still reach the server
+			tx.Rollback(context.WithoutCancel(ctx))
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, ambient)); err != nil {
+		if rollbackErr := tx.Rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rollbackErr))
+		}
+		return nil, err
+	}
+
+	// A failed commit has already rolled the transaction back
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return ambient, nil
+}
+
+// retryable reports whether err is a serialization failure (40001) or a deadlock (40P01)
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
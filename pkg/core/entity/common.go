@@ -1,5 +1,7 @@
 package entity
 
+import "slices"
+
 type Status string
 
 const (
@@ -13,6 +15,14 @@ const (
 	Deleted Status = "deleted"
 )
 
+// Statuses are the valid statuses
+var Statuses = []Status{Active, Inactive, Pending, Deleted}
+
+// Valid reports whether s is one of Statuses
+func (s Status) Valid() bool {
+	return slices.Contains(Statuses, s)
+}
+
 // Role represents the role of a user
 type Role string
 
@@ -23,6 +33,46 @@ const (
 	UserRole    Role = "user"
 )
 
+// Roles are the valid roles, from the highest to the lowest
+var Roles = []Role{AdminRole, ManagerRole, UserRole}
+
+// Valid reports whether r is one of Roles
+func (r Role) Valid() bool {
+	return slices.Contains(Roles, r)
+}
+
+// Outranks reports whether r comes before other in Roles, an invalid role outranks none
+func (r Role) Outranks(other Role) bool {
+	rank := slices.Index(Roles, r)
+	if rank < 0 {
+		return false
+	}
+	otherRank := slices.Index(Roles, other)
+	return otherRank < 0 || rank < otherRank
+}
+
+// Permission is an action a role can be granted, "<resource>:<action>"
+type Permission string
+
+const (
+	UsersList      Permission = "users:list"
+	UsersRead      Permission = "users:read"
+	UsersCreate    Permission = "users:create"
+	UsersUpdate    Permission = "users:update"
+	UsersDelete    Permission = "users:delete"
+	UsersTerminate Permission = "users:terminate"
+	// RolesAssign allows giving users a role other than UserRole
+	RolesAssign Permission = "roles:assign"
+)
+
+// Permissions are the valid permissions, granted to roles in the role_permissions table
+var Permissions = []Permission{UsersList, UsersRead, UsersCreate, UsersUpdate, UsersDelete, UsersTerminate, RolesAssign}
+
+// Valid reports whether p is one of Permissions
+func (p Permission) Valid() bool {
+	return slices.Contains(Permissions, p)
+}
+
 type AuthUserKey string
 
 const (
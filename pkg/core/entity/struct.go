@@ -9,3 +9,10 @@ type Pagination struct {
 	FirstPage    int  `json:"first_page"`
 	LastPage     int  `json:"last_page"`
 }
+
+// CursorPagination holds the opaque cursors of the pages around a keyset page
+type CursorPagination struct {
+	NextCursor *string `json:"next_cursor,omitempty"`
+	PrevCursor *string `json:"prev_cursor,omitempty"`
+	PageSize   int     `json:"page_size"`
+}
@@ -4,37 +4,82 @@ import (
 	"context"
 	"net/http"
 
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
 	"github.com/JubaerHossain/rootx/pkg/core/auth"
+	"github.com/JubaerHossain/rootx/pkg/core/cache"
 	"github.com/JubaerHossain/rootx/pkg/core/entity"
 	"github.com/JubaerHossain/rootx/pkg/utils"
 )
 
-func Authenticate(next http.Handler) http.Handler {
+// tokenKey is the context key of the verified access token
+type tokenKey struct{}
+
+// Authenticator authenticates requests by their bearer token
+type Authenticator struct {
+	cache    cache.CacheService
+	versions auth.TokenVersions
+}
+
+// NewAuthenticator creates an authenticator checking the revocation list of the cache and the token versions
+func NewAuthenticator(cacheService cache.CacheService, versions auth.TokenVersions) *Authenticator {
+	return &Authenticator{cache: cacheService, versions: versions}
+}
+
+// Authenticate lets through the requests whose token is valid, not revoked and of the current token version of its user
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check if the request is authenticated
-		token := r.Header.Get("Authorization")
-		if token == "" {
+		tokenString := r.Header.Get("Authorization")
+		if tokenString == "" {
 			utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: missing token")
 			return
 		}
 
 		// Verify the token
-		isValid, user, err := auth.VerifyToken(token)
+		token, err := auth.ParseToken(tokenString)
 		if err != nil {
 			utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
 			return
 		}
-		if !isValid {
-			utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: invalid token")
+
+		// Reject the tokens logged out one by one, then those older than the last password change or logout-all
+		revoked, err := auth.IsRevoked(r.Context(), a.cache, token.ID)
+		if err != nil {
+			utils.WriteError(w, err)
+			return
+		}
+		if revoked {
+			utils.WriteError(w, auth.ErrTokenRevoked)
+			return
+		}
+		version, err := a.versions.TokenVersion(r.Context(), token.User.ID)
+		if appError.Is(err, appError.NotFound) {
+			// The user was deleted
+			utils.WriteError(w, auth.ErrTokenOutdated)
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, err)
+			return
+		}
+		if version != token.Version {
+			utils.WriteError(w, auth.ErrTokenOutdated)
 			return
 		}
 
-		// Add the authenticated user to the request context
+		// Add the authenticated user and its token to the request context
 		ctx := r.Context()
-		ctx = context.WithValue(ctx, entity.AuthUser, user)
+		ctx = context.WithValue(ctx, entity.AuthUser, token.User)
+		ctx = context.WithValue(ctx, tokenKey{}, token)
 		r = r.WithContext(ctx)
 
 		// Call the next handler
 		next.ServeHTTP(w, r)
 	})
 }
+
+// TokenFrom returns the access token Authenticate verified for the request of the context
+func TokenFrom(ctx context.Context) (*auth.Token, bool) {
+	token, ok := ctx.Value(tokenKey{}).(*auth.Token)
+	return token, ok
+}
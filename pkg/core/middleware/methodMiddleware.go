@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/pkg/utils"
+)
+
+// routeMethods are the methods probed to find the ones a path allows
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// MethodNotAllowed answers requests whose path matches a route of the mux but not its method
+// with a JSON 405 and an Allow header listing the methods the path accepts
+func MethodNotAllowed(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		allowed := []string{}
+		for _, method := range routeMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != "" {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method "+r.Method+" not allowed, use "+strings.Join(allowed, ", "))
+	})
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/JubaerHossain/rootx/pkg/utils"
+)
+
+// ProblemDetails writes the errors of a request as RFC 7807 problem details (application/problem+json)
+// when ERROR_FORMAT=problem or when the request accepts application/problem+json. A request can still
+// ask for the plain JSON errors with an Accept header listing application/json but not problem+json.
+func ProblemDetails(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !wantsProblem(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(&utils.ProblemWriter{
+			ResponseWriter: w,
+			Instance:       r.URL.Path,
+			RequestID:      RequestIDFrom(r.Context()),
+		}, r)
+	})
+}
+
+// wantsProblem reports whether errors are answered as problem details for the request
+func wantsProblem(r *http.Request) bool {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil {
+			accepted[mediaType] = true
+		}
+	}
+
+	switch {
+	case accepted[utils.ProblemContentType]:
+		return true
+	case accepted["application/json"]:
+		return false
+	}
+	return config.GlobalConfig != nil && config.GlobalConfig.ErrorFormat == "problem"
+}
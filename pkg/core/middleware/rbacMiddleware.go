@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/JubaerHossain/rootx/pkg/core/auth"
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/JubaerHossain/rootx/pkg/utils"
+)
+
+// Authorizer guards routes with the permissions granted to the role of the authenticated user.
+// Its guards go inside Authenticate, which adds the user to the request context.
+type Authorizer struct {
+	permissions auth.RolePermissions
+}
+
+// NewAuthorizer creates an authorizer reading the permissions of the roles from permissions
+func NewAuthorizer(permissions auth.RolePermissions) *Authorizer {
+	return &Authorizer{permissions: permissions}
+}
+
+// RequirePermission lets through the users whose role grants the permission
+func (a *Authorizer) RequirePermission(permission entity.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := auth.User(r)
+			if err != nil {
+				utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+				return
+			}
+			if err := auth.Authorize(r.Context(), a.permissions, user, permission); err != nil {
+				utils.WriteError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSelfOrPermission lets through the users whose ID is the path value param, e.g. "id" for
+// /users/{id}, and the users whose role grants the permission
+func (a *Authorizer) RequireSelfOrPermission(param string, permission entity.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := auth.User(r)
+			if err != nil {
+				utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+				return
+			}
+			if id, err := strconv.ParseUint(r.PathValue(param), 10, strconv.IntSize); err == nil && uint(id) == user.ID {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := auth.Authorize(r.Context(), a.permissions, user, permission); err != nil {
+				utils.WriteError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole lets through the users having one of the roles, prefer RequirePermission so the roles
+// can be changed without a deployment
+func RequireRole(roles ...entity.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := auth.User(r)
+			if err != nil {
+				utils.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+				return
+			}
+			if !slices.Contains(roles, user.Role) {
+				utils.WriteError(w, auth.ErrForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package validation
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/go-playground/locales/bn"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+)
+
+// DefaultLanguage is the language of the messages when the request accepts none of the supported ones
+const DefaultLanguage = "en"
+
+// invalidKey is the message of the tags a language has no message for
+const invalidKey = "invalid"
+
+// englishMessages complete the built-in English messages of the validator with the custom tags.
+// {0} is the field and {1} the parameter of the tag.
+var englishMessages = map[string]string{
+	"phone":           "{0} must be a mobile number such as 01712345678",
+	"strong_password": "{0} must be 8 to 72 characters long with an upper case letter, a lower case letter, a digit and a symbol",
+	"role":            "{0} must be one of " + enumValues(entity.Roles),
+	"status":          "{0} must be one of " + enumValues(entity.Statuses),
+	invalidKey:        "{0} is invalid",
+}
+
+// bengaliMessages are the Bengali messages. A tag whose message depends on the kind of the field
+// has one message per kind, e.g. min-string, min-number and min-items.
+var bengaliMessages = map[string]string{
+	"required":        "{0} আবশ্যক",
+	"len-string":      "{0} অবশ্যই {1} অক্ষরের হতে হবে",
+	"len-number":      "{0} অবশ্যই {1} হতে হবে",
+	"len-items":       "{0}-এ অবশ্যই {1}টি আইটেম থাকতে হবে",
+	"min-string":      "{0} কমপক্ষে {1} অক্ষরের হতে হবে",
+	"min-number":      "{0} কমপক্ষে {1} হতে হবে",
+	"min-items":       "{0}-এ কমপক্ষে {1}টি আইটেম থাকতে হবে",
+	"max-string":      "{0} সর্বোচ্চ {1} অক্ষরের হতে পারে",
+	"max-number":      "{0} সর্বোচ্চ {1} হতে পারে",
+	"max-items":       "{0}-এ সর্বোচ্চ {1}টি আইটেম থাকতে পারে",
+	"gte":             "{0} অবশ্যই {1} বা তার বেশি হতে হবে",
+	"lte":             "{0} অবশ্যই {1} বা তার কম হতে হবে",
+	"gt":              "{0} অবশ্যই {1} এর বেশি হতে হবে",
+	"lt":              "{0} অবশ্যই {1} এর কম হতে হবে",
+	"eqfield":         "{0} অবশ্যই {1} এর সমান হতে হবে",
+	"oneof":           "{0} অবশ্যই এগুলোর একটি হতে হবে: {1}",
+	"email":           "{0} একটি সঠিক ইমেইল ঠিকানা হতে হবে",
+	"numeric":         "{0} অবশ্যই একটি সংখ্যা হতে হবে",
+	"phone":           "{0} অবশ্যই 01712345678 এর মতো একটি মোবাইল নম্বর হতে হবে",
+	"strong_password": "{0} অবশ্যই ৮ থেকে ৭২ অক্ষরের হতে হবে এবং এতে বড় হাতের অক্ষর, ছোট হাতের অক্ষর, সংখ্যা ও চিহ্ন থাকতে হবে",
+	"role":            "{0} অবশ্যই এগুলোর একটি হতে হবে: " + enumValues(entity.Roles),
+	"status":          "{0} অবশ্যই এগুলোর একটি হতে হবে: " + enumValues(entity.Statuses),
+	invalidKey:        "{0} সঠিক নয়",
+}
+
+// enumValues lists the values of an enum for a message
+func enumValues[T ~string](values []T) string {
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		names = append(names, string(value))
+	}
+	return strings.Join(names, ", ")
+}
+
+// newUniversalTranslator registers the English and Bengali messages of the validator
+func newUniversalTranslator(v *validator.Validate) *ut.UniversalTranslator {
+	english := en.New()
+	universal := ut.New(english, english, bn.New())
+
+	enTrans, _ := universal.GetTranslator("en")
+	if err := enTranslations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		panic("validation: failed to register the English messages: " + err.Error())
+	}
+	if err := registerMessages(v, enTrans, englishMessages); err != nil {
+		panic("validation: failed to register the English messages: " + err.Error())
+	}
+
+	bnTrans, _ := universal.GetTranslator("bn")
+	if err := registerMessages(v, bnTrans, bengaliMessages); err != nil {
+		panic("validation: failed to register the Bengali messages: " + err.Error())
+	}
+	return universal
+}
+
+// registerMessages adds the messages to the translator and registers their tags with the validator
+func registerMessages(v *validator.Validate, trans ut.Translator, messages map[string]string) error {
+	tags := map[string]bool{}
+	for key, text := range messages {
+		if err := trans.Add(key, text, true); err != nil {
+			return err
+		}
+		tag, _, _ := strings.Cut(key, "-")
+		tags[tag] = true
+	}
+	delete(tags, invalidKey)
+
+	for tag := range tags {
+		registered := func(ut.Translator) error { return nil }
+		if err := v.RegisterTranslation(tag, trans, registered, translateMessage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateMessage renders the message of the tag for the kind of the field, or of the tag
+func translateMessage(trans ut.Translator, fieldErr validator.FieldError) string {
+	for _, key := range []string{fieldErr.Tag() + "-" + kindOf(fieldErr.Kind()), fieldErr.Tag()} {
+		if message, err := trans.T(key, fieldErr.Field(), fieldErr.Param()); err == nil {
+			return message
+		}
+	}
+	return fieldErr.Error()
+}
+
+// kindOf groups the kinds of fields whose messages differ
+func kindOf(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return ""
+}
+
+// translate renders the message of a validation error, "{0} is invalid" when the language has none for its tag
+func translate(fieldErr validator.FieldError, trans ut.Translator) string {
+	if message := fieldErr.Translate(trans); message != fieldErr.Error() {
+		return message
+	}
+	if message, err := trans.T(invalidKey, fieldErr.Field()); err == nil {
+		return message
+	}
+	return fieldErr.Error()
+}
+
+// Translator returns the translator of the preferred supported language of an Accept-Language header,
+// e.g. "bn-BD,bn;q=0.9,en;q=0.8", English when it names none
+func Translator(acceptLanguage string) ut.Translator {
+	once.Do(setup)
+	for _, language := range languages(acceptLanguage) {
+		if trans, found := uni.GetTranslator(language); found {
+			return trans
+		}
+		// bn_BD falls back to bn
+		if base, _, ok := strings.Cut(language, "_"); ok {
+			if trans, found := uni.GetTranslator(base); found {
+				return trans
+			}
+		}
+	}
+	trans, _ := uni.GetTranslator(DefaultLanguage)
+	return trans
+}
+
+// languages returns the languages of an Accept-Language header as locales, by decreasing quality
+func languages(acceptLanguage string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+	accepted := []weighted{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 {
+				continue
+			}
+			quality = parsed
+		}
+		accepted = append(accepted, weighted{locale: strings.ReplaceAll(strings.ToLower(tag), "-", "_"), quality: quality})
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].quality > accepted[j].quality })
+
+	locales := make([]string, 0, len(accepted))
+	for _, language := range accepted {
+		locales = append(locales, language.locale)
+	}
+	return locales
+}
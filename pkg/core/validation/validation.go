@@ -0,0 +1,163 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/JubaerHossain/rootx/pkg/core/entity"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// phonePattern is a Bangladeshi mobile number as it is stored, e.g. 01712345678
+var phonePattern = regexp.MustCompile(`^01[3-9][0-9]{8}$`)
+
+// Password rules of the strong_password tag, bcrypt ignores the bytes after the 72nd
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// PhoneLength is the length of a mobile number accepted by the phone tag
+const PhoneLength = 11
+
+var (
+	validate *validator.Validate
+	uni      *ut.UniversalTranslator
+	once     sync.Once
+)
+
+// Validator returns the shared validator. It reports fields by their JSON names and knows the tags
+// phone, strong_password, role and status besides the built-in ones.
+func Validator() *validator.Validate {
+	once.Do(setup)
+	return validate
+}
+
+// Struct validates the validate tags of a struct, see Validator
+func Struct(s interface{}) error {
+	return Validator().Struct(s)
+}
+
+// setup builds the validator and registers its tags and translations
+func setup() {
+	validate = validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(jsonName)
+
+	validations := map[string]validator.Func{
+		"phone":           isPhone,
+		"strong_password": isStrongPassword,
+		"role":            isEnum[entity.Role],
+		"status":          isEnum[entity.Status],
+	}
+	for tag, fn := range validations {
+		if err := validate.RegisterValidation(tag, fn); err != nil {
+			panic("validation: failed to register " + tag + ": " + err.Error())
+		}
+	}
+
+	uni = newUniversalTranslator(validate)
+}
+
+// TagMaxLength returns the length of the longest value a custom tag accepts, 0 for the other tags,
+// e.g. for the migration generator to size the column of a field validated with role
+func TagMaxLength(tag string) int {
+	switch tag {
+	case "phone":
+		return PhoneLength
+	case "strong_password":
+		return MaxPasswordLength
+	case "role":
+		return longest(entity.Roles)
+	case "status":
+		return longest(entity.Statuses)
+	}
+	return 0
+}
+
+// longest returns the length of the longest value of an enum
+func longest[T ~string](values []T) int {
+	n := 0
+	for _, value := range values {
+		n = max(n, len(value))
+	}
+	return n
+}
+
+// jsonName names a field by its json tag, so errors point at the keys of the request body
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// isPhone validates a Bangladeshi mobile number
+func isPhone(fl validator.FieldLevel) bool {
+	return phonePattern.MatchString(fl.Field().String())
+}
+
+// isStrongPassword validates a password of 8 to 72 bytes with an upper case letter, a lower case letter,
+// a digit and a symbol
+func isStrongPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return false
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	return upper && lower && digit && symbol
+}
+
+// isEnum validates a value of an enum type such as entity.Role
+func isEnum[T interface {
+	~string
+	Valid() bool
+}](fl validator.FieldLevel) bool {
+	return T(fl.Field().String()).Valid()
+}
+
+// Messages returns the messages of the validation errors of err in the language of the translator,
+// keyed by the JSON path of the field, e.g. "phone" or "items[0].name". It returns nil when err
+// is not a validation error.
+func Messages(err error, trans ut.Translator) map[string]string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	messages := make(map[string]string, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		messages[fieldPath(fieldErr)] = translate(fieldErr, trans)
+	}
+	return messages
+}
+
+// fieldPath is the namespace of the field without the name of the validated struct
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".")
+	if !found {
+		return fieldErr.Field()
+	}
+	return path
+}
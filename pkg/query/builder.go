@@ -0,0 +1,311 @@
+package utilQuery
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// identifierPattern matches a column or table name, optionally qualified, e.g. users.created_at
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ValidIdentifier reports whether name can be used as a column or table name in SQL text
+func ValidIdentifier(name string) bool {
+	return identifierPattern.MatchString(name)
+}
+
+// Op is a filter operator of a query string, written field[op]=value, plain field=value is eq
+type Op string
+
+const (
+	OpEq   Op = "eq"
+	OpIn   Op = "in"
+	OpLike Op = "like"
+	OpGte  Op = "gte"
+	OpLte  Op = "lte"
+	OpGt   Op = "gt"
+	OpLt   Op = "lt"
+)
+
+// operators maps the range operators to SQL
+var operators = map[Op]string{OpEq: "=", OpGte: ">=", OpLte: "<=", OpGt: ">", OpLt: "<"}
+
+// FieldType is how the values of a filterable field are parsed before being bound
+type FieldType int
+
+const (
+	FieldText FieldType = iota
+	FieldInt
+	FieldTime
+	FieldBool
+)
+
+// Field declares a query string parameter that filters on a column
+type Field struct {
+	// Param is the query string parameter, e.g. "status"
+	Param string
+	// Column defaults to Param
+	Column string
+	Type   FieldType
+	// Ops are the operators accepted, eq only when empty
+	Ops []Op
+	// Allowed restricts the values, any value is accepted when empty
+	Allowed []string
+}
+
+// SelectBuilder builds a SELECT for pgx. Values are always bound as parameters and the
+// column names it is given are checked, so query strings can safely drive it.
+type SelectBuilder struct {
+	table   string
+	columns []string
+	filter  Filter
+	orderBy string
+	limit   int
+	offset  int
+	err     error
+}
+
+// Select starts a query on a table, selecting the given columns or * when none
+func Select(table string, columns ...string) *SelectBuilder {
+	b := &SelectBuilder{table: table, columns: columns}
+	b.check(table)
+	for _, column := range columns {
+		b.check(column)
+	}
+	return b
+}
+
+// check records an error when name is not a plain identifier
+func (b *SelectBuilder) check(name string) bool {
+	if !ValidIdentifier(name) {
+		b.fail(fmt.Errorf("invalid identifier %q", name))
+		return false
+	}
+	return true
+}
+
+// fail records the first error, it is returned by SQL
+func (b *SelectBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Where adds a condition written by the developer with "?" placeholders for its values
+func (b *SelectBuilder) Where(condition string, args ...interface{}) *SelectBuilder {
+	b.filter.Add(condition, args...)
+	return b
+}
+
+// Eq adds column = value
+func (b *SelectBuilder) Eq(column string, value interface{}) *SelectBuilder {
+	return b.compare(column, OpEq, value)
+}
+
+// In adds column = ANY(values), matching nothing when values is empty
+func (b *SelectBuilder) In(column string, values ...interface{}) *SelectBuilder {
+	if b.check(column) {
+		b.filter.Add(column+" = ANY(?)", values)
+	}
+	return b
+}
+
+// Like adds a case insensitive search of text anywhere in any of the columns
+func (b *SelectBuilder) Like(text string, columns ...string) *SelectBuilder {
+	conditions := []string{}
+	args := []interface{}{}
+	for _, column := range columns {
+		if !b.check(column) {
+			return b
+		}
+		conditions = append(conditions, column+" ILIKE ?")
+		args = append(args, LikePattern(text))
+	}
+	if len(conditions) > 0 {
+		b.filter.Add("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	return b
+}
+
+// Range adds from <= column <= to, a nil bound is left open
+func (b *SelectBuilder) Range(column string, from, to interface{}) *SelectBuilder {
+	if from != nil {
+		b.compare(column, OpGte, from)
+	}
+	if to != nil {
+		b.compare(column, OpLte, to)
+	}
+	return b
+}
+
+func (b *SelectBuilder) compare(column string, op Op, value interface{}) *SelectBuilder {
+	if b.check(column) {
+		b.filter.Add(column+" "+operators[op]+" ?", value)
+	}
+	return b
+}
+
+// Filter adds the conditions of the query string parameters matching the fields, see ParseConditions
+func (b *SelectBuilder) Filter(queryValues map[string][]string, fields ...Field) *SelectBuilder {
+	conditions, err := ParseConditions(queryValues, fields...)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	return b.Conditions(conditions...)
+}
+
+// Conditions adds parsed conditions, see ParseConditions
+func (b *SelectBuilder) Conditions(conditions ...Condition) *SelectBuilder {
+	for _, condition := range conditions {
+		switch condition.Op {
+		case OpIn:
+			values, _ := condition.Value.([]interface{})
+			b.In(condition.Column, values...)
+		case OpLike:
+			b.Like(fmt.Sprint(condition.Value), condition.Column)
+		default:
+			if _, ok := operators[condition.Op]; !ok {
+				b.fail(fmt.Errorf("unknown operator %q", condition.Op))
+				return b
+			}
+			b.compare(condition.Column, condition.Op, condition.Value)
+		}
+	}
+	return b
+}
+
+// accepts reports whether the field has the operator of a field[op] parameter
+func (f Field) accepts(param string) bool {
+	for _, op := range f.Ops {
+		if param == f.Param+"["+string(op)+"]" {
+			return true
+		}
+	}
+	return len(f.Ops) == 0 && param == f.Param+"[eq]"
+}
+
+// parse converts a query string value to the type of the field. A date ending a
+// range is moved to the end of the day so the range includes it.
+func (f Field) parse(param, value string, endOfRange bool) (interface{}, error) {
+	if len(f.Allowed) > 0 {
+		allowed := false
+		for _, candidate := range f.Allowed {
+			allowed = allowed || candidate == value
+		}
+		if !allowed {
+			return nil, InvalidParam("%s must be one of %s", param, strings.Join(f.Allowed, ", "))
+		}
+	}
+
+	switch f.Type {
+	case FieldInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, InvalidParam("%s must be an integer", param)
+		}
+		return n, nil
+	case FieldBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, InvalidParam("%s must be true or false", param)
+		}
+		return b, nil
+	case FieldTime:
+		return DateParam(url.Values{param: {value}}, param, endOfRange)
+	}
+	return value, nil
+}
+
+// OrderBy sorts on a whitelisted column, field is checked against allowed and
+// direction must be asc or desc. defaultOrder is used when field is empty.
+func (b *SelectBuilder) OrderBy(field, direction, defaultOrder string, allowed ...string) *SelectBuilder {
+	columns := map[string]string{}
+	for _, column := range allowed {
+		if b.check(column) {
+			columns[column] = column
+		}
+	}
+
+	orderBy, err := SortFromQuery(url.Values{"orderBy": {field}, "sortBy": {direction}}, columns, defaultOrder)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	b.orderBy = orderBy
+	return b
+}
+
+// Sort orders on a parsed sort whose column must be one of allowed, defaultOrder is used for the zero Sort
+func (b *SelectBuilder) Sort(sort Sort, defaultOrder string, allowed ...string) *SelectBuilder {
+	direction := "asc"
+	if sort.Desc {
+		direction = "desc"
+	}
+	return b.OrderBy(sort.Column, direction, defaultOrder, allowed...)
+}
+
+// ThenBy breaks ties of the order on a column, usually a unique one so rows do not move between pages
+func (b *SelectBuilder) ThenBy(column string) *SelectBuilder {
+	if b.check(column) && b.orderBy != "" {
+		b.orderBy += ", " + column
+	}
+	return b
+}
+
+// OrderByQuery sorts by the orderBy and sortBy query parameters, see OrderBy
+func (b *SelectBuilder) OrderByQuery(queryValues map[string][]string, defaultOrder string, allowed ...string) *SelectBuilder {
+	q := url.Values(queryValues)
+	return b.OrderBy(q.Get("orderBy"), q.Get("sortBy"), defaultOrder, allowed...)
+}
+
+// Page limits the query to a page
+func (b *SelectBuilder) Page(page Page) *SelectBuilder {
+	b.limit, b.offset = page.Size, page.Offset()
+	return b
+}
+
+// Keyset limits the query to the keyset page of the cursor and sorts it along the key
+func (b *SelectBuilder) Keyset(keyset *Keyset) *SelectBuilder {
+	keyset.Where(&b.filter)
+	b.orderBy = keyset.OrderBy()
+	b.limit, b.offset = keyset.Limit(), 0
+	return b
+}
+
+// SQL returns the query and its arguments, or the first error met while building it
+func (b *SelectBuilder) SQL() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	columns := "*"
+	if len(b.columns) > 0 {
+		columns = strings.Join(b.columns, ", ")
+	}
+
+	query := "SELECT " + columns + " FROM " + b.table + b.filter.Where()
+	args := append([]interface{}{}, b.filter.Args()...)
+	if b.orderBy != "" {
+		query += " ORDER BY " + b.orderBy
+	}
+	if b.limit > 0 {
+		args = append(args, b.limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if b.offset > 0 {
+		args = append(args, b.offset)
+		query += " OFFSET $" + strconv.Itoa(len(args))
+	}
+	return query, args, nil
+}
+
+// CountSQL returns the query counting the rows matching the conditions, ignoring order and page
+func (b *SelectBuilder) CountSQL() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return "SELECT COUNT(*) FROM " + b.table + b.filter.Where(), b.filter.Args(), nil
+}
@@ -0,0 +1,228 @@
+package utilQuery
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFilterAdd(t *testing.T) {
+	tests := []struct {
+		name      string
+		add       func(f *Filter)
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{name: "no condition", add: func(f *Filter) {}, wantWhere: ""},
+		{
+			name:      "placeholders are numbered across conditions",
+			add:       func(f *Filter) { f.Add("status = ?", "active"); f.Add("role = ?", "admin") },
+			wantWhere: " WHERE status = $1 AND role = $2",
+			wantArgs:  []interface{}{"active", "admin"},
+		},
+		{
+			name:      "several placeholders in a condition",
+			add:       func(f *Filter) { f.Add("id = ?", 1); f.Add("(name ILIKE ? OR phone ILIKE ?)", "%a%", "%b%") },
+			wantWhere: " WHERE id = $1 AND (name ILIKE $2 OR phone ILIKE $3)",
+			wantArgs:  []interface{}{1, "%a%", "%b%"},
+		},
+		{
+			name:      "condition without argument",
+			add:       func(f *Filter) { f.Add("deleted_at IS NULL"); f.Add("id > ?", 5) },
+			wantWhere: " WHERE deleted_at IS NULL AND id > $1",
+			wantArgs:  []interface{}{5},
+		},
+		{
+			name:      "casts are kept",
+			add:       func(f *Filter) { f.Add("created_at > ?::text::timestamptz", "2024-01-01") },
+			wantWhere: " WHERE created_at > $1::text::timestamptz",
+			wantArgs:  []interface{}{"2024-01-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &Filter{}
+			tt.add(filter)
+			if filter.Where() != tt.wantWhere {
+				t.Errorf("Where() = %q, want %q", filter.Where(), tt.wantWhere)
+			}
+			if fmt.Sprint(filter.Args()) != fmt.Sprint(tt.wantArgs) {
+				t.Errorf("Args() = %v, want %v", filter.Args(), tt.wantArgs)
+			}
+			if want := fmt.Sprintf("$%d", len(tt.wantArgs)+1); filter.Placeholder(1) != want {
+				t.Errorf("Placeholder(1) = %s, want %s", filter.Placeholder(1), want)
+			}
+		})
+	}
+}
+
+// userFields are the filterable fields of the builder tests
+var userFields = []Field{
+	{Param: "status", Allowed: []string{"active", "inactive"}},
+	{Param: "role", Ops: []Op{OpEq, OpIn}},
+	{Param: "name", Ops: []Op{OpLike}},
+	{Param: "id", Type: FieldInt, Ops: []Op{OpGt, OpLt}},
+	{Param: "created_at", Type: FieldTime, Ops: []Op{OpGte, OpLte}},
+}
+
+func TestSelectBuilder(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 30, 23, 59, 59, 999999000, time.UTC)
+
+	tests := []struct {
+		name      string
+		build     func() *SelectBuilder
+		wantSQL   string
+		wantCount string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "all columns",
+			build:     func() *SelectBuilder { return Select("users") },
+			wantSQL:   "SELECT * FROM users",
+			wantCount: "SELECT COUNT(*) FROM users",
+		},
+		{
+			name: "conditions order and page",
+			build: func() *SelectBuilder {
+				return Select("users", "id", "name").Eq("status", "active").In("role", "admin", "manager").
+					OrderBy("name", "desc", "id DESC", "name", "id").ThenBy("id").Page(Page{Number: 3, Size: 10})
+			},
+			wantSQL:   "SELECT id, name FROM users WHERE status = $1 AND role = ANY($2) ORDER BY name DESC, id LIMIT $3 OFFSET $4",
+			wantCount: "SELECT COUNT(*) FROM users WHERE status = $1 AND role = ANY($2)",
+			wantArgs:  []interface{}{"active", []interface{}{"admin", "manager"}, 10, 20},
+		},
+		{
+			name: "first page has no offset",
+			build: func() *SelectBuilder {
+				return Select("users").OrderBy("", "", "created_at DESC").Page(Page{Number: 1, Size: 5})
+			},
+			wantSQL:   "SELECT * FROM users ORDER BY created_at DESC LIMIT $1",
+			wantCount: "SELECT COUNT(*) FROM users",
+			wantArgs:  []interface{}{5},
+		},
+		{
+			name:      "like escapes the wildcards of the text",
+			build:     func() *SelectBuilder { return Select("users").Like("50%_a", "name", "phone") },
+			wantSQL:   "SELECT * FROM users WHERE (name ILIKE $1 OR phone ILIKE $2)",
+			wantCount: "SELECT COUNT(*) FROM users WHERE (name ILIKE $1 OR phone ILIKE $2)",
+			wantArgs:  []interface{}{`%50\%\_a%`, `%50\%\_a%`},
+		},
+		{
+			name:      "open range",
+			build:     func() *SelectBuilder { return Select("users").Range("id", 10, nil) },
+			wantSQL:   "SELECT * FROM users WHERE id >= $1",
+			wantCount: "SELECT COUNT(*) FROM users WHERE id >= $1",
+			wantArgs:  []interface{}{10},
+		},
+		{
+			name: "query string filters",
+			build: func() *SelectBuilder {
+				return Select("users").Filter(url.Values{
+					"status":          {"active"},
+					"role[in]":        {"admin, manager"},
+					"name[like]":      {"jub"},
+					"id[gt]":          {"7"},
+					"created_at[gte]": {"2024-01-01"},
+					"created_at[lte]": {"2024-06-30"},
+				}, userFields...)
+			},
+			wantSQL: "SELECT * FROM users WHERE status = $1 AND role = ANY($2) AND (name ILIKE $3) AND id > $4 " +
+				"AND created_at >= $5 AND created_at <= $6",
+			wantCount: "SELECT COUNT(*) FROM users WHERE status = $1 AND role = ANY($2) AND (name ILIKE $3) AND id > $4 " +
+				"AND created_at >= $5 AND created_at <= $6",
+			wantArgs: []interface{}{"active", []interface{}{"admin", "manager"}, "%jub%", int64(7), from, to},
+		},
+		{
+			name:      "role[eq] is role",
+			build:     func() *SelectBuilder { return Select("users").Filter(url.Values{"role[eq]": {"admin"}}, userFields...) },
+			wantSQL:   "SELECT * FROM users WHERE role = $1",
+			wantCount: "SELECT COUNT(*) FROM users WHERE role = $1",
+			wantArgs:  []interface{}{"admin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := tt.build().SQL()
+			if err != nil {
+				t.Fatalf("SQL() error = %v", err)
+			}
+			if query != tt.wantSQL {
+				t.Errorf("SQL() = %q\nwant %q", query, tt.wantSQL)
+			}
+			if fmt.Sprint(args) != fmt.Sprint(tt.wantArgs) {
+				t.Errorf("SQL() args = %v, want %v", args, tt.wantArgs)
+			}
+
+			count, _, err := tt.build().CountSQL()
+			if err != nil || count != tt.wantCount {
+				t.Errorf("CountSQL() = %q, %v, want %q", count, err, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestSelectBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		build        func() *SelectBuilder
+		invalidParam bool
+	}{
+		{name: "table", build: func() *SelectBuilder { return Select("users; DROP TABLE users") }},
+		{name: "column", build: func() *SelectBuilder { return Select("users", "id", "name, password") }},
+		{name: "condition column", build: func() *SelectBuilder { return Select("users").Eq("1=1 OR id", 1) }},
+		{name: "then by column", build: func() *SelectBuilder { return Select("users").OrderBy("", "", "id").ThenBy("id--") }},
+		{
+			name:         "order by column",
+			build:        func() *SelectBuilder { return Select("users").OrderBy("password", "", "id", "name") },
+			invalidParam: true,
+		},
+		{
+			name:         "sort direction",
+			build:        func() *SelectBuilder { return Select("users").OrderBy("name", "sideways", "id", "name") },
+			invalidParam: true,
+		},
+		{
+			name:         "value outside the allowed ones",
+			build:        func() *SelectBuilder { return Select("users").Filter(url.Values{"status": {"banned"}}, userFields...) },
+			invalidParam: true,
+		},
+		{
+			name: "operator the field does not accept",
+			build: func() *SelectBuilder {
+				return Select("users").Filter(url.Values{"status[like]": {"act"}}, userFields...)
+			},
+			invalidParam: true,
+		},
+		{
+			name:         "integer",
+			build:        func() *SelectBuilder { return Select("users").Filter(url.Values{"id[gt]": {"seven"}}, userFields...) },
+			invalidParam: true,
+		},
+		{
+			name: "date",
+			build: func() *SelectBuilder {
+				return Select("users").Filter(url.Values{"created_at[gte]": {"01/01/2024"}}, userFields...)
+			},
+			invalidParam: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := tt.build()
+			if _, _, err := builder.SQL(); err == nil {
+				t.Fatal("SQL() succeeded")
+			} else if errors.Is(err, ErrInvalidParam) != tt.invalidParam {
+				t.Errorf("SQL() error = %v, invalid parameter %v, want %v", err, errors.Is(err, ErrInvalidParam), tt.invalidParam)
+			}
+			if _, _, err := builder.CountSQL(); err == nil {
+				t.Error("CountSQL() succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package utilQuery
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/logger"
+)
+
+// CursorParam is the query parameter selecting keyset pagination, "?cursor=" asks for the first page
+const CursorParam = "cursor"
+
+// KeyColumn is a column of a keyset sort key, Type is the SQL type cursor values are cast to
+type KeyColumn struct {
+	Name string
+	Type string
+}
+
+// Keyset pages through a query along an ordered sort key whose last column is unique, e.g. created_at then id.
+// Unlike offsets, the cost of a page does not grow with its depth.
+type Keyset struct {
+	Columns []KeyColumn
+	Desc    bool
+	Size    int
+	// cursor is the decoded cursor of the request, nil on the first page
+	cursor *cursorPayload
+}
+
+// cursorPayload is the signed content of a cursor token
+type cursorPayload struct {
+	// Key identifies the sort key the values belong to
+	Key string `json:"k"`
+	// Values are the sort key of the row the page starts after
+	Values []string `json:"v"`
+	// Back is set when the page lies before that row
+	Back bool `json:"b,omitempty"`
+}
+
+// CursorMode reports whether the request opted into keyset pagination with the cursor parameter
+func CursorMode(queryValues map[string][]string) bool {
+	_, ok := queryValues[CursorParam]
+	return ok
+}
+
+// NewKeyset pages size rows at a time along a sort key from a cursor, "" for the first page,
+// rejecting cursors that were tampered with or issued for another sort key
+func NewKeyset(cursor string, size int, desc bool, columns ...KeyColumn) (*Keyset, error) {
+	keyset := &Keyset{Columns: columns, Desc: desc, Size: size}
+	if cursor == "" {
+		return keyset, nil
+	}
+
+	payload, err := decodeCursor(cursor)
+	if err != nil || payload.Key != keyset.key() || len(payload.Values) != len(columns) {
+		return nil, InvalidParam("cursor is invalid or was issued for another sort order")
+	}
+	keyset.cursor = payload
+	return keyset, nil
+}
+
+// Where adds the condition selecting the rows after, or before, the cursor
+func (k *Keyset) Where(filter *Filter) {
+	if k.cursor == nil {
+		return
+	}
+
+	names := make([]string, 0, len(k.Columns))
+	placeholders := make([]string, 0, len(k.Columns))
+	args := make([]interface{}, 0, len(k.Columns))
+	for i, column := range k.Columns {
+		names = append(names, column.Name)
+		// Values travel as text so they keep their full precision
+		placeholders = append(placeholders, "?::text::"+column.Type)
+		args = append(args, k.cursor.Values[i])
+	}
+
+	operator := ">"
+	if k.Desc != k.cursor.Back {
+		operator = "<"
+	}
+	filter.Add("("+strings.Join(names, ", ")+") "+operator+" ("+strings.Join(placeholders, ", ")+")", args...)
+}
+
+// OrderBy returns the ORDER BY clause, reversed when paging back
+func (k *Keyset) OrderBy() string {
+	direction := "ASC"
+	if k.Desc != (k.cursor != nil && k.cursor.Back) {
+		direction = "DESC"
+	}
+
+	columns := make([]string, 0, len(k.Columns))
+	for _, column := range k.Columns {
+		columns = append(columns, column.Name+" "+direction)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// Limit returns the number of rows to fetch, one more than the page to know whether another page follows
+func (k *Keyset) Limit() int {
+	return k.Size + 1
+}
+
+// key describes the sort key, cursors are only valid for the key they were issued for
+func (k *Keyset) key() string {
+	names := make([]string, 0, len(k.Columns))
+	for _, column := range k.Columns {
+		names = append(names, column.Name)
+	}
+	direction := "asc"
+	if k.Desc {
+		direction = "desc"
+	}
+	return strings.Join(names, ",") + ":" + direction
+}
+
+// KeysetPage trims the rows fetched with the limit of the keyset to the page, puts them back in
+// order when paging back and issues the cursors of the neighbouring pages. key returns the values
+// of the sort key of a row.
+func KeysetPage[T any](k *Keyset, rows []T, key func(T) []interface{}) ([]T, coreEntity.CursorPagination) {
+	back := k.cursor != nil && k.cursor.Back
+	hasMore := len(rows) > k.Size
+	if hasMore {
+		rows = rows[:k.Size]
+	}
+	if back {
+		slices.Reverse(rows)
+	}
+
+	pagination := coreEntity.CursorPagination{PageSize: k.Size}
+	if len(rows) == 0 {
+		return rows, pagination
+	}
+
+	// Going forward there is a previous page unless this is the first one,
+	// going back there is always a next page, the one we came from
+	if hasMore || back {
+		next := k.encode(key(rows[len(rows)-1]), false)
+		pagination.NextCursor = &next
+	}
+	if (back && hasMore) || (!back && k.cursor != nil) {
+		prev := k.encode(key(rows[0]), true)
+		pagination.PrevCursor = &prev
+	}
+	return rows, pagination
+}
+
+// encode issues the cursor of the page after, or before, the row with the given sort key values
+func (k *Keyset) encode(values []interface{}, back bool) string {
+	payload := cursorPayload{Key: k.key(), Back: back}
+	for _, value := range values {
+		payload.Values = append(payload.Values, cursorValue(value))
+	}
+
+	content, _ := json.Marshal(payload)
+	encoded := base64.RawURLEncoding.EncodeToString(content)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(encoded))
+}
+
+// decodeCursor verifies the signature of a cursor token and decodes it
+func decodeCursor(token string) (*cursorPayload, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signCursor(encoded)) {
+		return nil, fmt.Errorf("bad cursor signature")
+	}
+
+	content, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	payload := &cursorPayload{}
+	if err := json.Unmarshal(content, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// cursorSecret signs cursors, a random one until InitCursor loads CURSOR_SECRET so a
+// known key never signs them
+var cursorSecret = randomSecret()
+
+// InitCursor loads CURSOR_SECRET, production refuses to start without it
+func InitCursor(cfg *config.Config) error {
+	if cfg.CursorSecret != "" {
+		SetCursorSecret([]byte(cfg.CursorSecret))
+		return nil
+	}
+	if cfg.IsProduction() {
+		return fmt.Errorf("CURSOR_SECRET is required in production, pagination cursors cannot be signed")
+	}
+	logger.Info("No CURSOR_SECRET, signing pagination cursors with a generated secret lost on restart")
+	return nil
+}
+
+// SetCursorSecret replaces the secret cursors are signed with
+func SetCursorSecret(secret []byte) {
+	cursorSecret = secret
+}
+
+// randomSecret returns 32 random bytes
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate cursor secret: %v", err))
+	}
+	return secret
+}
+
+// signCursor signs the encoded payload of a cursor
+func signCursor(encoded string) []byte {
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// cursorValue renders a sort key value as text Postgres can cast back without losing precision
+func cursorValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,223 @@
+package utilQuery
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+)
+
+var keyColumns = []KeyColumn{{Name: "created_at", Type: "timestamptz"}, {Name: "id", Type: "bigint"}}
+
+// withCursorSecret signs the cursors of a test with a known secret
+func withCursorSecret(t *testing.T, secret string) {
+	t.Helper()
+	saved := cursorSecret
+	SetCursorSecret([]byte(secret))
+	t.Cleanup(func() { SetCursorSecret(saved) })
+}
+
+// keysetAt returns the keyset of a request passing the cursor
+func keysetAt(t *testing.T, cursor string, desc bool) *Keyset {
+	t.Helper()
+	keyset, err := NewKeyset(cursor, 2, desc, keyColumns...)
+	if err != nil {
+		t.Fatalf("NewKeyset(%q) error = %v", cursor, err)
+	}
+	return keyset
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	withCursorSecret(t, "secret")
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 123456789, time.UTC)
+	token := keysetAt(t, "", true).encode([]interface{}{createdAt, uint(42)}, true)
+
+	keyset := keysetAt(t, token, true)
+	if keyset.cursor == nil || !keyset.cursor.Back {
+		t.Fatalf("cursor = %+v, want a back cursor", keyset.cursor)
+	}
+	if got := strings.Join(keyset.cursor.Values, ","); got != "2024-05-01T10:30:00.123456789Z,42" {
+		t.Errorf("cursor values = %s, want the full precision time and the id", got)
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	withCursorSecret(t, "secret")
+	token := keysetAt(t, "", true).encode([]interface{}{"2024-05-01T10:30:00Z", 42}, false)
+	encoded, signature, _ := strings.Cut(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"k":"created_at,id:desc","v":["2024-05-01T10:30:00Z","1"]}`))
+
+	tests := []struct {
+		name   string
+		cursor string
+		desc   bool
+		secret string
+	}{
+		{name: "no signature", cursor: encoded, desc: true},
+		{name: "forged payload", cursor: forged + "." + signature, desc: true},
+		{name: "altered signature", cursor: encoded + "." + signature[1:], desc: true},
+		{name: "signature is not base64", cursor: encoded + ".!!", desc: true},
+		{name: "other secret", cursor: token, desc: true, secret: "rotated"},
+		{name: "other sort order", cursor: token, desc: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.secret != "" {
+				withCursorSecret(t, tt.secret)
+			}
+			if _, err := NewKeyset(tt.cursor, 10, tt.desc, keyColumns...); err == nil {
+				t.Fatal("NewKeyset() accepted the cursor")
+			}
+		})
+	}
+
+	// A cursor issued for another sort key is refused even when correctly signed
+	if _, err := NewKeyset(token, 10, true, KeyColumn{Name: "id", Type: "bigint"}); err == nil {
+		t.Error("NewKeyset() accepted a cursor of another sort key")
+	}
+}
+
+func TestInitCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{name: "secret", cfg: config.Config{AppEnv: "production", CursorSecret: "secret"}},
+		{name: "generated secret outside production", cfg: config.Config{AppEnv: "development"}},
+		{name: "production without secret", cfg: config.Config{AppEnv: "production"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withCursorSecret(t, "before")
+			err := InitCursor(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InitCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.cfg.CursorSecret != "" && string(cursorSecret) != tt.cfg.CursorSecret {
+				t.Errorf("cursor secret = %q, want CURSOR_SECRET", cursorSecret)
+			}
+		})
+	}
+}
+
+// row is a row of a keyset page, sorted on its id
+type row struct{ id int }
+
+func rowKey(r row) []interface{} { return []interface{}{"2024-01-01T00:00:00Z", r.id} }
+
+// ids returns the ids of the rows
+func ids(rows []row) []int {
+	got := []int{}
+	for _, r := range rows {
+		got = append(got, r.id)
+	}
+	return got
+}
+
+func TestKeysetPage(t *testing.T) {
+	withCursorSecret(t, "secret")
+	first := keysetAt(t, "", false)
+	forward := first.encode(rowKey(row{2}), false)
+	back := first.encode(rowKey(row{5}), true)
+
+	tests := []struct {
+		name     string
+		cursor   string
+		fetched  []row
+		want     []int
+		wantNext int
+		wantPrev int
+	}{
+		{name: "first page", fetched: []row{{1}, {2}, {3}}, want: []int{1, 2}, wantNext: 2},
+		{name: "only page", fetched: []row{{1}}, want: []int{1}},
+		{name: "middle page", cursor: forward, fetched: []row{{3}, {4}, {5}}, want: []int{3, 4}, wantNext: 4, wantPrev: 3},
+		{name: "last page", cursor: forward, fetched: []row{{3}}, want: []int{3}, wantPrev: 3},
+		// Paging back fetches the rows in reverse order, the page is put back in order
+		{name: "back", cursor: back, fetched: []row{{4}, {3}, {2}}, want: []int{3, 4}, wantNext: 4, wantPrev: 3},
+		{name: "back to the first page", cursor: back, fetched: []row{{4}, {3}}, want: []int{3, 4}, wantNext: 4},
+		{name: "empty page", cursor: forward, fetched: []row{}, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyset := keysetAt(t, tt.cursor, false)
+			rows, pagination := KeysetPage(keyset, tt.fetched, rowKey)
+
+			if got := ids(rows); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("page = %v, want %v", got, tt.want)
+			}
+			if pagination.PageSize != 2 {
+				t.Errorf("page size = %d, want 2", pagination.PageSize)
+			}
+			checkCursor(t, "next", pagination.NextCursor, tt.wantNext, false)
+			checkCursor(t, "prev", pagination.PrevCursor, tt.wantPrev, true)
+		})
+	}
+}
+
+// checkCursor checks that a cursor starts after, or before when back, the row with the wanted id, 0 meaning no cursor
+func checkCursor(t *testing.T, name string, cursor *string, wantID int, back bool) {
+	t.Helper()
+	if wantID == 0 {
+		if cursor != nil {
+			t.Errorf("%s cursor is set, want none", name)
+		}
+		return
+	}
+	if cursor == nil {
+		t.Fatalf("%s cursor is not set", name)
+	}
+
+	keyset := keysetAt(t, *cursor, false)
+	if keyset.cursor.Back != back || keyset.cursor.Values[1] != cursorValue(wantID) {
+		t.Errorf("%s cursor = %+v, want row %d with back %v", name, keyset.cursor, wantID, back)
+	}
+}
+
+func TestKeysetQuery(t *testing.T) {
+	withCursorSecret(t, "secret")
+	first := keysetAt(t, "", true)
+	tests := []struct {
+		name      string
+		cursor    string
+		wantWhere string
+		wantOrder string
+	}{
+		{name: "first page", wantOrder: "created_at DESC, id DESC"},
+		{
+			name:      "forward",
+			cursor:    first.encode(rowKey(row{7}), false),
+			wantWhere: " WHERE (created_at, id) < ($1::text::timestamptz, $2::text::bigint)",
+			wantOrder: "created_at DESC, id DESC",
+		},
+		{
+			name:      "back",
+			cursor:    first.encode(rowKey(row{7}), true),
+			wantWhere: " WHERE (created_at, id) > ($1::text::timestamptz, $2::text::bigint)",
+			wantOrder: "created_at ASC, id ASC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyset := keysetAt(t, tt.cursor, true)
+			filter := &Filter{}
+			keyset.Where(filter)
+			if filter.Where() != tt.wantWhere {
+				t.Errorf("Where() = %q, want %q", filter.Where(), tt.wantWhere)
+			}
+			if keyset.OrderBy() != tt.wantOrder {
+				t.Errorf("OrderBy() = %q, want %q", keyset.OrderBy(), tt.wantOrder)
+			}
+			if keyset.Limit() != 3 {
+				t.Errorf("Limit() = %d, want the page size and one", keyset.Limit())
+			}
+		})
+	}
+}
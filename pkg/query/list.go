@@ -0,0 +1,110 @@
+package utilQuery
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Condition is a filter on a column, parsed from a query string parameter such as role[in]=admin,manager.
+// Value is a []interface{} for OpIn and the searched text for OpLike.
+type Condition struct {
+	Column string
+	Op     Op
+	Value  interface{}
+}
+
+// Sort orders a list on a column, the zero Sort keeps the default order of the list
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// ListParams is a list request, parsed from the query string by ParseList
+type ListParams struct {
+	Page       Page
+	Conditions []Condition
+	Sort       Sort
+}
+
+// ParseList reads the page, the filters of the fields and the sort of a list, orderBy must be one of sorts
+func ParseList(queryValues url.Values, fields []Field, sorts ...string) (ListParams, error) {
+	conditions, err := ParseConditions(queryValues, fields...)
+	if err != nil {
+		return ListParams{}, err
+	}
+	sort, err := ParseSort(queryValues, sorts...)
+	if err != nil {
+		return ListParams{}, err
+	}
+	return ListParams{Page: PageFromQuery(queryValues), Conditions: conditions, Sort: sort}, nil
+}
+
+// ParseSort reads the orderBy and sortBy (asc or desc) query parameters, orderBy must be one of columns
+func ParseSort(queryValues url.Values, columns ...string) (Sort, error) {
+	allowed := map[string]string{}
+	for _, column := range columns {
+		allowed[column] = column
+	}
+	// SortFromQuery validates both parameters, the clause it builds is not needed
+	if _, err := SortFromQuery(queryValues, allowed, ""); err != nil {
+		return Sort{}, err
+	}
+	return Sort{Column: queryValues.Get("orderBy"), Desc: strings.EqualFold(queryValues.Get("sortBy"), "desc")}, nil
+}
+
+// ParseConditions reads the query string parameters matching the fields, e.g. status=active,
+// role[in]=admin,manager, name[like]=jub or created_at[gte]=2024-01-01
+func ParseConditions(queryValues url.Values, fields ...Field) ([]Condition, error) {
+	conditions := []Condition{}
+	for _, field := range fields {
+		column := field.Column
+		if column == "" {
+			column = field.Param
+		}
+		ops := field.Ops
+		if len(ops) == 0 {
+			ops = []Op{OpEq}
+		}
+
+		for _, op := range ops {
+			param := field.Param + "[" + string(op) + "]"
+			value := queryValues.Get(param)
+			if op == OpEq && value == "" {
+				param = field.Param
+				value = queryValues.Get(param)
+			}
+			if value == "" {
+				continue
+			}
+
+			switch op {
+			case OpIn:
+				values := []interface{}{}
+				for _, item := range strings.Split(value, ",") {
+					parsed, err := field.parse(param, strings.TrimSpace(item), false)
+					if err != nil {
+						return nil, err
+					}
+					values = append(values, parsed)
+				}
+				conditions = append(conditions, Condition{Column: column, Op: op, Value: values})
+			case OpLike:
+				conditions = append(conditions, Condition{Column: column, Op: op, Value: value})
+			default:
+				parsed, err := field.parse(param, value, op == OpLte)
+				if err != nil {
+					return nil, err
+				}
+				conditions = append(conditions, Condition{Column: column, Op: op, Value: parsed})
+			}
+		}
+
+		// Reject operators the field does not accept rather than silently ignoring them
+		for name := range queryValues {
+			if strings.HasPrefix(name, field.Param+"[") && !field.accepts(name) {
+				return nil, InvalidParam("%s is not supported", name)
+			}
+		}
+	}
+	return conditions, nil
+}
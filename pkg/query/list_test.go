@@ -0,0 +1,84 @@
+package utilQuery
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	fields := []Field{
+		{Param: "status", Ops: []Op{OpEq, OpIn}, Allowed: []string{"active", "inactive"}},
+		{Param: "name", Ops: []Op{OpLike}},
+		{Param: "age", Column: "users.age", Type: FieldInt, Ops: []Op{OpGte}},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    ListParams
+		wantErr bool
+	}{
+		{name: "defaults", query: "", want: ListParams{Page: Page{Number: 1, Size: DefaultPageSize}, Conditions: []Condition{}}},
+		{
+			name:  "page filters and sort",
+			query: "page=2&pageSize=5&status[in]=active,inactive&name[like]=jub&age[gte]=18&orderBy=name&sortBy=DESC",
+			want: ListParams{
+				Page: Page{Number: 2, Size: 5},
+				Conditions: []Condition{
+					{Column: "status", Op: OpIn, Value: []interface{}{"active", "inactive"}},
+					{Column: "name", Op: OpLike, Value: "jub"},
+					{Column: "users.age", Op: OpGte, Value: int64(18)},
+				},
+				Sort: Sort{Column: "name", Desc: true},
+			},
+		},
+		{name: "unknown sort column", query: "orderBy=password", wantErr: true},
+		{name: "bad direction", query: "orderBy=name&sortBy=up", wantErr: true},
+		{name: "value not allowed", query: "status=banned", wantErr: true},
+		{name: "operator not accepted", query: "name[gte]=a", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			got, err := ParseList(query, fields, "id", "name")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidParam) {
+					t.Fatalf("ParseList() error = %v, want an invalid parameter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseList() error = %v", err)
+			}
+			if fmt.Sprintf("%+v", got) != fmt.Sprintf("%+v", tt.want) {
+				t.Errorf("ParseList() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionsAndSort(t *testing.T) {
+	query, args, err := Select("users").
+		Conditions(Condition{Column: "role", Op: OpIn, Value: []interface{}{"admin"}}, Condition{Column: "id", Op: OpGt, Value: 3}).
+		Sort(Sort{Column: "name"}, "id DESC", "name").
+		SQL()
+	if err != nil {
+		t.Fatalf("SQL() error = %v", err)
+	}
+	if want := "SELECT * FROM users WHERE role = ANY($1) AND id > $2 ORDER BY name ASC"; query != want {
+		t.Errorf("SQL() = %q, want %q", query, want)
+	}
+	if len(args) != 2 {
+		t.Errorf("SQL() args = %v, want 2", args)
+	}
+
+	if _, _, err := Select("users").Conditions(Condition{Column: "id", Op: "between", Value: 1}).SQL(); err == nil {
+		t.Error("SQL() accepted an unknown operator")
+	}
+	if _, _, err := Select("users").Sort(Sort{Column: "password"}, "id", "name").SQL(); err == nil {
+		t.Error("SQL() sorted on a column that is not allowed")
+	}
+}
@@ -0,0 +1,186 @@
+package utilQuery
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+)
+
+// ErrInvalidParam is wrapped by the errors caused by a bad query string parameter
+var ErrInvalidParam = errors.New("invalid query parameter")
+
+// InvalidParam returns a BadRequest error describing a bad query string parameter, it wraps ErrInvalidParam
+func InvalidParam(format string, args ...interface{}) error {
+	return appError.Wrap(appError.BadRequest, "invalid_query_parameter", fmt.Sprintf(format, args...), ErrInvalidParam)
+}
+
+// Default and maximum number of items per page
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// Page is the page requested by the page and pageSize query parameters
+type Page struct {
+	Number int
+	Size   int
+}
+
+// PageFromQuery reads the page and pageSize query parameters, pageSize is capped at MaxPageSize
+func PageFromQuery(queryValues map[string][]string) Page {
+	q := url.Values(queryValues)
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
+	switch {
+	case pageSize <= 0:
+		pageSize = DefaultPageSize
+	case pageSize > MaxPageSize:
+		pageSize = MaxPageSize
+	}
+
+	return Page{Number: page, Size: pageSize}
+}
+
+// Offset returns the number of items before the page
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.Size
+}
+
+// NewPagination describes the page among totalItems items
+func NewPagination(page Page, totalItems int) coreEntity.Pagination {
+	var nextPage, previousPage *int
+	if page.Number > 1 {
+		prevPage := page.Number - 1
+		previousPage = &prevPage
+	}
+	if page.Offset()+page.Size < totalItems {
+		nextPageValue := page.Number + 1
+		nextPage = &nextPageValue
+	}
+
+	lastPage := max(int(math.Ceil(float64(totalItems)/float64(page.Size))), 1)
+	return coreEntity.Pagination{
+		TotalItems:   totalItems,
+		TotalPages:   int(math.Ceil(float64(totalItems) / float64(page.Size))),
+		CurrentPage:  page.Number,
+		NextPage:     nextPage,
+		PreviousPage: previousPage,
+		FirstPage:    1,
+		LastPage:     lastPage,
+	}
+}
+
+// Filter collects the conditions of a WHERE clause with their arguments.
+// Conditions use "?" placeholders that are numbered $1, $2... in order.
+type Filter struct {
+	conditions []string
+	args       []interface{}
+}
+
+// Add appends a condition, e.g. filter.Add("status = ?", status)
+func (f *Filter) Add(condition string, args ...interface{}) {
+	for _, arg := range args {
+		f.args = append(f.args, arg)
+		condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(f.args)), 1)
+	}
+	f.conditions = append(f.conditions, condition)
+}
+
+// Where returns the WHERE clause, empty when there is no condition
+func (f *Filter) Where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// Args returns the arguments of the conditions
+func (f *Filter) Args() []interface{} {
+	return f.args
+}
+
+// Placeholder returns the placeholder of the next argument, e.g. for LIMIT and OFFSET
+func (f *Filter) Placeholder(offset int) string {
+	return "$" + strconv.Itoa(len(f.args)+offset)
+}
+
+// OneOf reads a query parameter that must be one of the allowed values, empty when absent
+func OneOf(queryValues map[string][]string, name string, allowed ...string) (string, error) {
+	value := url.Values(queryValues).Get(name)
+	if value == "" {
+		return "", nil
+	}
+	for _, candidate := range allowed {
+		if value == candidate {
+			return value, nil
+		}
+	}
+	return "", InvalidParam("%s must be one of %s", name, strings.Join(allowed, ", "))
+}
+
+// DateParam reads a date (2006-01-02) or RFC 3339 time query parameter, the zero time when absent.
+// A date bound ending a range is moved to the end of the day so the range includes it.
+func DateParam(queryValues map[string][]string, name string, endOfRange bool) (time.Time, error) {
+	value := url.Values(queryValues).Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, InvalidParam("%s must be a date like 2006-01-02", name)
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+	return t, nil
+}
+
+// LikePattern builds an ILIKE pattern matching text anywhere, escaping its wildcards
+func LikePattern(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(text) + "%"
+}
+
+// SortFromQuery builds an ORDER BY clause from the orderBy and sortBy (asc or desc) query parameters.
+// Only the keys of columns can be sorted on, they map to the SQL expression to sort by.
+func SortFromQuery(queryValues map[string][]string, columns map[string]string, defaultOrder string) (string, error) {
+	q := url.Values(queryValues)
+	orderBy := q.Get("orderBy")
+	if orderBy == "" {
+		return defaultOrder, nil
+	}
+
+	column, ok := columns[orderBy]
+	if !ok {
+		allowed := make([]string, 0, len(columns))
+		for name := range columns {
+			allowed = append(allowed, name)
+		}
+		sort.Strings(allowed)
+		return "", InvalidParam("orderBy must be one of %s", strings.Join(allowed, ", "))
+	}
+
+	switch direction := strings.ToLower(q.Get("sortBy")); direction {
+	case "", "asc":
+		return column + " ASC", nil
+	case "desc":
+		return column + " DESC", nil
+	default:
+		return "", InvalidParam("sortBy must be asc or desc")
+	}
+}
@@ -11,92 +11,28 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/JubaerHossain/rootx/pkg/core/validation"
 	"github.com/JubaerHossain/rootx/pkg/utils"
-	"github.com/go-playground/validator/v10"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 func Pagination(query *gorm.DB, queryValues map[string][]string) *gorm.DB {
-	q := url.Values(queryValues)
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-		page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
-	switch {
-	case pageSize <= 0:
-		pageSize = 10
-	}
-
-	offset := (page - 1) * pageSize
-
-	query = query.Offset(offset).Limit(pageSize) // Pagination
-
-	return query
-
+	page := PageFromQuery(queryValues)
+	return query.Offset(page.Offset()).Limit(page.Size) // Pagination
 }
 
 func Paginate(query *gorm.DB, queryValues map[string][]string, totalItems int) (*gorm.DB, coreEntity.Pagination) {
-	q := url.Values(queryValues)
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-		page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
-	switch {
-	case pageSize <= 0:
-		pageSize = 10
-	}
-
-	offset := (page - 1) * pageSize
-
-	var nextPage, previousPage *int
-	if page > 1 {
-		prevPage := page - 1
-		previousPage = &prevPage
-	}
-	// You may need to adjust the condition based on your total items count
-	if offset+pageSize < totalItems {
-		nextPageValue := page + 1
-		nextPage = &nextPageValue
-	}
-
-	pagination := coreEntity.Pagination{
-		TotalItems:   totalItems,
-		TotalPages:   int(math.Ceil(float64(totalItems) / float64(pageSize))),
-		CurrentPage:  page,
-		NextPage:     nextPage,
-		PreviousPage: previousPage,
-		FirstPage:    1,
-		LastPage:     int(math.Ceil(float64(totalItems) / float64(pageSize))),
-	}
-
-	return query.Offset(offset).Limit(pageSize), pagination
+	page := PageFromQuery(queryValues)
+	return query.Offset(page.Offset()).Limit(page.Size), NewPagination(page, totalItems)
 }
 
 func RawPagination(sqlQuery string, queryValues map[string][]string) string {
-	q := url.Values(queryValues)
-	page, _ := strconv.Atoi(q.Get("page"))
-	if page <= 0 {
-		page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
-	switch {
-	case pageSize <= 0:
-		pageSize = 10
-	}
-
-	offset := (page - 1) * pageSize
-
-	sqlQuery += " LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa(offset)
-
-	return sqlQuery
+	page := PageFromQuery(queryValues)
+	return sqlQuery + " LIMIT " + strconv.Itoa(page.Size) + " OFFSET " + strconv.Itoa(page.Offset())
 }
 
 func HashPassword(password string) (string, error) {
@@ -120,10 +56,10 @@ func BodyParse(s interface{}, w http.ResponseWriter, r *http.Request, isValidati
 	}
 
 	if isValidation {
-		validate := validator.New()
-		validateErr := validate.Struct(s)
-		if validateErr != nil {
-			utils.WriteJSONEValidation(w, http.StatusBadRequest, validateErr.(validator.ValidationErrors))
+		// Messages in the language of the request, English by default
+		if validateErr := validation.Struct(s); validateErr != nil {
+			trans := validation.Translator(r.Header.Get("Accept-Language"))
+			utils.ResponseValidation(w, http.StatusBadRequest, validation.Messages(validateErr, trans))
 			return validateErr
 		}
 	}
@@ -137,10 +73,8 @@ func BodyParseValidation(s interface{}, w http.ResponseWriter, r *http.Request,
 	}
 
 	if isValidation {
-		validate := validator.New()
-		validateErr := validate.Struct(s)
-		if validateErr != nil {
-			return validateErr.(validator.ValidationErrors)
+		if validateErr := validation.Struct(s); validateErr != nil {
+			return validateErr
 		}
 	}
 	return nil
@@ -155,21 +89,22 @@ func Round(num float64, places int) float64 {
 	return rounded
 }
 
+// OrderBy builds an ORDER BY clause from the orderBy and sortBy query parameters, created_at asc by default.
+// The column must be an identifier and the direction asc or desc, anything else falls back to the default.
+// Prefer SortFromQuery or SelectBuilder.OrderBy, which also restrict the columns to a whitelist.
 func OrderBy(queryValues map[string][]string) string {
 	q := url.Values(queryValues)
-	orderBy := "created_at"
-	if conditions, ok := q["orderBy"]; ok && len(conditions) > 0 {
-		orderBy = conditions[0]
+	orderBy := q.Get("orderBy")
+	if !ValidIdentifier(orderBy) {
+		orderBy = "created_at"
 	}
 
-	sortOrder := "asc"
-	if conditions, ok := q["sortBy"]; ok && len(conditions) > 0 {
-		sortOrder = conditions[0]
+	sortOrder := strings.ToLower(q.Get("sortBy"))
+	if sortOrder != "desc" {
+		sortOrder = "asc"
 	}
 
-	orderBy = orderBy + " " + sortOrder
-
-	return orderBy
+	return orderBy + " " + sortOrder
 }
 
 func GenerateUniqueNumber(length int) (string, error) {
@@ -201,6 +136,10 @@ func MatchTableFields(db *gorm.DB, table string, where map[string]interface{}, o
 	values := []interface{}{}
 
 	for key, value := range where {
+		// Keys become SQL text, only plain column names are accepted
+		if !ValidIdentifier(key) {
+			return false, fmt.Errorf("invalid column name %q", key)
+		}
 		whereQuery += key + " = ? OR "
 		values = append(values, value)
 	}
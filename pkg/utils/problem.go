@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body, code, errors and request_id are extensions
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      string      `json:"code,omitempty"`
+	Errors    interface{} `json:"errors,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// ProblemWriter is a response writer whose errors are written as problem details
+type ProblemWriter struct {
+	http.ResponseWriter
+	// Instance identifies the occurrence, the path the client requested before any prefix is stripped
+	Instance  string
+	RequestID string
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (w *ProblemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// problemWriter returns the ProblemWriter w is or wraps, nil when errors are written as plain JSON
+func problemWriter(w http.ResponseWriter) *ProblemWriter {
+	for {
+		switch writer := w.(type) {
+		case *ProblemWriter:
+			return writer
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// problemType returns the type URI of a problem code, PROBLEM_TYPE_BASE followed by the code,
+// or about:blank when there is no code or no base
+func problemType(code string) string {
+	if code == "" || config.GlobalConfig == nil || config.GlobalConfig.ProblemTypeBase == "" {
+		return "about:blank"
+	}
+	return config.GlobalConfig.ProblemTypeBase + code
+}
+
+// writeProblem writes a problem details response
+func (w *ProblemWriter) writeProblem(status int, code, detail string, errors interface{}) {
+	problem := Problem{
+		Type:      problemType(code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  w.Instance,
+		Code:      code,
+		Errors:    errors,
+		RequestID: w.RequestID,
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/go-playground/validator/v10"
+	appError "github.com/JubaerHossain/rootx/pkg/core/apperror"
+	"github.com/JubaerHossain/rootx/pkg/core/logger"
+	"github.com/JubaerHossain/rootx/pkg/core/validation"
+	"go.uber.org/zap"
 )
 
 // Response represents a standardized JSON response format.
 type Response struct {
 	Success bool        `json:"success"`
+	Code    string      `json:"code,omitempty"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 }
@@ -67,6 +71,10 @@ func JsonResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 
 // WriteJSONError writes a JSON error response with the specified status code and message.
 func WriteJSONError(w http.ResponseWriter, statusCode int, message string) {
+	if problem := problemWriter(w); problem != nil {
+		problem.writeProblem(statusCode, "", message, nil)
+		return
+	}
 	response := Response{
 		Success: false,
 		Message: message,
@@ -81,10 +89,38 @@ func WriteJSONError(w http.ResponseWriter, statusCode int, message string) {
 	}
 }
 
-func WriteJSONEValidation(w http.ResponseWriter, statusCode int, error interface{}) {
-	errors := make(map[string]string)
-	for _, err := range error.(validator.ValidationErrors) {
-		errors[err.Field()] = err.Field() + " is " + err.Tag() + " " + err.Param()
+// WriteError writes an error with the HTTP status and code of its kind, see appError.
+// Internal errors are logged and answered without their details.
+func WriteError(w http.ResponseWriter, err error) {
+	appErr := appError.As(err)
+	if appErr.Kind == appError.Internal {
+		logger.Error("Internal server error", zap.Error(err))
+	}
+	if problem := problemWriter(w); problem != nil {
+		problem.writeProblem(appError.HTTPStatus(appErr), appErr.Code, appErr.Message, nil)
+		return
+	}
+	response := Response{
+		Success: false,
+		Code:    appErr.Code,
+		Message: appErr.Message,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appError.HTTPStatus(appErr))
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// WriteJSONEValidation writes the English messages of validation errors keyed by their JSON paths,
+// see validation.Messages for messages in the language of the request
+func WriteJSONEValidation(w http.ResponseWriter, statusCode int, err error) {
+	errors := validation.Messages(err, validation.Translator(validation.DefaultLanguage))
+	if problem := problemWriter(w); problem != nil {
+		problem.writeProblem(statusCode, "validation_error", "Validation error", errors)
+		return
 	}
 	response := ErrorResponse{
 		Success: false,
@@ -94,14 +130,17 @@ func WriteJSONEValidation(w http.ResponseWriter, statusCode int, error interface
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	err := json.NewEncoder(w).Encode(response)
-	if err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, "Validation Error", http.StatusInternalServerError)
 		return
 	}
 }
 
 func ResponseValidation(w http.ResponseWriter, statusCode int, errors interface{}) error {
+	if problem := problemWriter(w); problem != nil {
+		problem.writeProblem(statusCode, "validation_error", "Validation error", errors)
+		return nil
+	}
 	response := ErrorResponse{
 		Success: false,
 		Message: "Validation error",
@@ -1,5 +0,0 @@
--- Seeder for table roles
-
-INSERT INTO roles (name, description, created_at, updated_at) VALUES
-    ('Value1', 'Description 1', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
-    ('Value2', 'Description 2', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);